@@ -1,22 +1,36 @@
 package barquesubmit
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/evergreen-ci/gimlet"
 	"github.com/evergreen-ci/utility"
+	"github.com/jpillora/backoff"
 	"github.com/mongodb/amboy"
 	"github.com/mongodb/curator/repobuilder"
+	"github.com/mongodb/grip"
 	"github.com/pkg/errors"
 )
 
+// Sentinel errors returned (wrapped) by handleError, so callers of
+// Login, SubmitJob, and CheckJobStatus can branch on the failure mode
+// with errors.Is instead of string-matching the error message.
+var (
+	ErrUnauthorized      = errors.New("barque request unauthorized")
+	ErrNotFound          = errors.New("barque resource not found")
+	ErrConflict          = errors.New("barque request conflicts with existing resource")
+	ErrServerUnavailable = errors.New("barque service unavailable")
+)
+
 const (
 	barqueAPIKeyHeader  = "Api-Key"
 	barqueAPIUserHeader = "Api-User"
@@ -24,11 +38,14 @@ const (
 
 type Client struct {
 	baseURL  string
-	username string
-	apiKey   string
+	provider CredentialProvider
 }
 
-func New(baseURL string) (*Client, error) {
+// New builds a Client for the barque service at baseURL. provider
+// supplies the credentials attached to every request and may be nil,
+// in which case requests go out unauthenticated until SetCredentials,
+// SetCredentialProvider, or Login is called.
+func New(baseURL string, provider CredentialProvider) (*Client, error) {
 	if !strings.HasPrefix(baseURL, "http") {
 		return nil, errors.New("malformed url")
 	}
@@ -42,7 +59,8 @@ func New(baseURL string) (*Client, error) {
 	}
 
 	return &Client{
-		baseURL: baseURL,
+		baseURL:  baseURL,
+		provider: provider,
 	}, nil
 }
 
@@ -61,20 +79,40 @@ func (c *Client) makeRequest(ctx context.Context, url, method string, body io.Re
 	}
 	req = req.WithContext(ctx)
 
-	if c.apiKey == "" {
+	if c.provider == nil {
 		return req, nil
 	}
 
-	if c.username != "" {
-		req.Header[barqueAPIUserHeader] = []string{c.username}
+	user, token, scheme, err := c.provider.Credentials(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem resolving request credentials")
 	}
-	if c.apiKey != "" {
-		req.Header[barqueAPIKeyHeader] = []string{c.apiKey}
+
+	switch scheme {
+	case AuthSchemeBearer:
+		req.Header.Set("Authorization", "Bearer "+token)
+	case AuthSchemeAPIKey, "":
+		if user != "" {
+			req.Header[barqueAPIUserHeader] = []string{user}
+		}
+		if token != "" {
+			req.Header[barqueAPIKeyHeader] = []string{token}
+		}
+	default:
+		return nil, errors.Errorf("unrecognized auth scheme %q", scheme)
 	}
 
 	return req, nil
 }
 
+// SetCredentialProvider replaces the credentials used for every
+// subsequent request. It's the general form of SetCredentials, for
+// callers using one of the non-static providers (netrc, OS keyring,
+// OIDC) instead of a fixed username/API key pair.
+func (c *Client) SetCredentialProvider(provider CredentialProvider) {
+	c.provider = provider
+}
+
 type userCredentials struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
@@ -85,8 +123,13 @@ type userAPIKeyResponse struct {
 	Key      string `json:"key"`
 }
 
+// Login exchanges username/password for a barque API key and installs
+// it as the Client's credentials. It's one way to obtain a
+// CredentialProvider among several (see SetCredentials,
+// SetCredentialProvider, and the Netrc/Keyring/OIDC providers) rather
+// than the only way to authenticate a Client.
 func (c *Client) Login(ctx context.Context, username, password string) error {
-	fmt.Printf(">>> calling client login with username=%s and password=%s\n", username, password)
+	grip.Debugf("logging in to barque as '%s'", username)
 	client := utility.GetDefaultHTTPRetryableClient()
 	defer utility.PutHTTPClient(client)
 
@@ -95,18 +138,15 @@ func (c *Client) Login(ctx context.Context, username, password string) error {
 		return errors.Wrap(err, "problem marshaling login payload")
 	}
 
-	fmt.Printf(">>> making request with payload: %s\n", string(payload))
 	req, err := c.makeRequest(ctx, "admin/login", http.MethodPost, bytes.NewBuffer(payload))
 	if err != nil {
 		return errors.Wrap(err, "problem building login request")
 	}
-	fmt.Printf(">>> constructed req: %+v\n", req)
 
 	resp, err := client.Do(req)
 	if err != nil {
 		return errors.Wrap(err, "problem making login request")
 	}
-	fmt.Printf(">>> got res: %+v\n", resp)
 
 	if resp.StatusCode != http.StatusOK {
 		return c.handleError(resp.StatusCode, resp.Body)
@@ -121,14 +161,17 @@ func (c *Client) Login(ctx context.Context, username, password string) error {
 		return errors.Errorf("service returned logically inconsistent credentials")
 	}
 
-	c.apiKey = data.Key
-	c.username = data.Username
+	c.provider = NewStaticCredentialProvider(data.Username, data.Key)
 	return nil
 }
 
+// SetCredentials is sugar for SetCredentialProvider with a
+// StaticCredentialProvider, for callers that already have a
+// username/API key pair (from Login elsewhere, a config file, and so
+// on) and don't need one of the other CredentialProvider
+// implementations.
 func (c *Client) SetCredentials(username, key string) {
-	c.username = username
-	c.apiKey = key
+	c.provider = NewStaticCredentialProvider(username, key)
 }
 
 func (c *Client) SubmitJob(ctx context.Context, opts repobuilder.JobOptions) (string, error) {
@@ -173,16 +216,95 @@ type JobStatus struct {
 	QueueStatus amboy.QueueStats    `json:"queue_status"`
 	HasErrors   bool                `json:"has_errors"`
 	Error       string              `json:"error"`
+	Progress    Progress            `json:"progress"`
+}
+
+// Progress describes how far along a running job is. The barque
+// server populates it from the job's amboy scopes, so CI pipelines
+// polling WaitJob/StreamJobs can surface more than just "running".
+type Progress struct {
+	Stage   string  `json:"stage"`
+	Percent float64 `json:"percent"`
+}
+
+// WaitJobOptions bounds the exponential backoff WaitJob and
+// StreamJobs' long-poll fallback use between requests, for the case
+// where a long-poll round trip comes back without a status change.
+type WaitJobOptions struct {
+	MinPollInterval time.Duration
+	MaxPollInterval time.Duration
+}
+
+// NewDefaultWaitJobOptions returns the poll interval bounds WaitJob
+// and StreamJobs use when the caller doesn't provide its own.
+func NewDefaultWaitJobOptions() WaitJobOptions {
+	return WaitJobOptions{
+		MinPollInterval: time.Second,
+		MaxPollInterval: 30 * time.Second,
+	}
+}
+
+func (o WaitJobOptions) backoff() *backoff.Backoff {
+	min := o.MinPollInterval
+	if min <= 0 {
+		min = time.Second
+	}
+
+	max := o.MaxPollInterval
+	if max <= 0 {
+		max = 30 * time.Second
+	}
+
+	return &backoff.Backoff{Min: min, Max: max, Factor: 2, Jitter: true}
+}
+
+// sleepOrCancel waits for d, returning early with ctx.Err() if ctx is
+// canceled first, so WaitJob and StreamJobs never block past their
+// caller's deadline between long-poll attempts.
+func sleepOrCancel(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
 
-func (c *Client) handleError(code int, body io.ReadCloser) gimlet.ErrorResponse {
+// handleError decodes a non-200 response body into a gimlet.ErrorResponse,
+// falling back to wrapping the raw body with the status code when the
+// body isn't valid JSON (or doesn't carry a message), and classifies
+// the response against a small set of sentinel errors so callers can
+// use errors.Is instead of comparing status codes or messages
+// themselves.
+func (c *Client) handleError(code int, body io.ReadCloser) error {
 	data, err := ioutil.ReadAll(body)
 	if err != nil {
-		panic(err)
+		return errors.Wrap(err, "reading error response body")
+	}
+
+	resp := gimlet.ErrorResponse{StatusCode: code}
+	if jsonErr := json.Unmarshal(data, &resp); jsonErr != nil || resp.Message == "" {
+		resp.Message = string(data)
+	}
+	resp.StatusCode = code
+
+	grip.Error(resp)
+
+	switch code {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return errors.Wrap(ErrUnauthorized, resp.Error())
+	case http.StatusNotFound:
+		return errors.Wrap(ErrNotFound, resp.Error())
+	case http.StatusConflict:
+		return errors.Wrap(ErrConflict, resp.Error())
+	case http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return errors.Wrap(ErrServerUnavailable, resp.Error())
+	default:
+		return resp
 	}
-	fmt.Printf(">>> handling error: %s\n", string(data))
-	gout := gimlet.ErrorResponse{}
-	return gout
 }
 
 func (c *Client) CheckJobStatus(ctx context.Context, id string) (*JobStatus, error) {
@@ -209,3 +331,553 @@ func (c *Client) CheckJobStatus(ctx context.Context, id string) (*JobStatus, err
 
 	return out, nil
 }
+
+// JobSubmission is a single job in a SubmitJobs batch. DependsOn lists
+// the IDs (from an earlier entry in the same batch, or a previous
+// SubmitJobs/SubmitJob call) that must complete before the barque
+// server's amboy queue releases this job, so callers can express
+// "rebuild this repo only after those finish" graphs.
+type JobSubmission struct {
+	Options   repobuilder.JobOptions
+	DependsOn []string
+}
+
+// jobSubmissionPayload is the wire form of a JobSubmission: the
+// repobuilder.JobOptions fields flattened alongside depends_on, which
+// JobOptions itself has no notion of.
+type jobSubmissionPayload struct {
+	repobuilder.JobOptions
+	DependsOn []string `json:"depends_on,omitempty"`
+}
+
+// SubmitResult is one entry in SubmitJobs' response. A submission
+// failure is reported here, in Err, rather than by aborting the rest
+// of the batch or by the error SubmitJobs itself returns.
+type SubmitResult struct {
+	ID  string
+	Err error
+}
+
+type submitResultPayload struct {
+	ID    string `json:"id"`
+	Error string `json:"error,omitempty"`
+}
+
+// SubmitJobs posts a batch of job submissions to repobuilder/batch in
+// a single round trip. Each submission may set JobSubmission.DependsOn
+// to express a dependency graph; the server wires the corresponding
+// amboy scopes/dependencies on its queue. A failure on one job in the
+// batch doesn't abort the rest: check each SubmitResult.Err rather
+// than relying on the error SubmitJobs itself returns, which only
+// reports a problem with the batch request as a whole.
+func (c *Client) SubmitJobs(ctx context.Context, jobs []JobSubmission) ([]SubmitResult, error) {
+	client := utility.GetDefaultHTTPRetryableClient()
+	defer utility.PutHTTPClient(client)
+
+	payload := make([]jobSubmissionPayload, len(jobs))
+	for i, job := range jobs {
+		payload[i] = jobSubmissionPayload{JobOptions: job.Options, DependsOn: job.DependsOn}
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem marshaling batch job payload")
+	}
+
+	req, err := c.makeRequest(ctx, "repobuilder/batch", http.MethodPost, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "problem building batch job request")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem making batch job submission request")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.handleError(resp.StatusCode, resp.Body)
+	}
+
+	var out []submitResultPayload
+	if err = gimlet.GetJSON(resp.Body, &out); err != nil {
+		return nil, errors.Wrap(err, "problem reading body of batch job response")
+	}
+
+	results := make([]SubmitResult, len(out))
+	for i, r := range out {
+		result := SubmitResult{ID: r.ID}
+		if r.Error != "" {
+			result.Err = errors.New(r.Error)
+		}
+		results[i] = result
+	}
+
+	return results, nil
+}
+
+// CheckJobStatuses fetches the status of many jobs in a single
+// request, returned keyed by ID. An id the server doesn't recognize
+// is simply absent from the map, rather than causing the whole call
+// to fail.
+func (c *Client) CheckJobStatuses(ctx context.Context, ids []string) (map[string]*JobStatus, error) {
+	client := utility.GetDefaultHTTPRetryableClient()
+	defer utility.PutHTTPClient(client)
+
+	payload, err := json.Marshal(ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem marshaling job id list")
+	}
+
+	req, err := c.makeRequest(ctx, "repobuilder/check", http.MethodPost, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, errors.Wrap(err, "problem building bulk status request")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "problem making bulk status request")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.handleError(resp.StatusCode, resp.Body)
+	}
+
+	var out []JobStatus
+	if err = gimlet.GetJSON(resp.Body, &out); err != nil {
+		return nil, errors.Wrap(err, "problem reading body of bulk status response")
+	}
+
+	statuses := make(map[string]*JobStatus, len(out))
+	for i := range out {
+		statuses[out[i].ID] = &out[i]
+	}
+
+	return statuses, nil
+}
+
+// CancelJob asks barque to cancel id, whether it's still queued,
+// already running, or (for jobs that support cooperative cancellation)
+// mid-flight -- the operator-facing equivalent of having to SSH into
+// barque to kill a stuck repo rebuild.
+func (c *Client) CancelJob(ctx context.Context, id string) error {
+	client := utility.GetDefaultHTTPRetryableClient()
+	defer utility.PutHTTPClient(client)
+
+	req, err := c.makeRequest(ctx, strings.Join([]string{"repobuilder", id}, "/"), http.MethodDelete, nil)
+	if err != nil {
+		return errors.Wrap(err, "problem building cancel request")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "problem making cancel request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return c.handleError(resp.StatusCode, resp.Body)
+	}
+
+	return nil
+}
+
+// RetryOptions configures RetryJob's re-submission of a job.
+type RetryOptions struct {
+	// ResetScopes discards the amboy scopes the original job held, so
+	// the retry isn't blocked behind dependents of the failed run.
+	ResetScopes bool
+	// Overrides, if set, replaces the original job's JobOptions
+	// wholesale on resubmission -- e.g. to retry with a narrower
+	// package list after a partial failure -- rather than resubmitting
+	// the job unchanged.
+	Overrides *repobuilder.JobOptions
+}
+
+type retryOptionsPayload struct {
+	ResetScopes bool                    `json:"reset_scopes"`
+	Overrides   *repobuilder.JobOptions `json:"overrides,omitempty"`
+}
+
+// RetryJob resubmits id as a new job and returns its new ID. See
+// RetryOptions for how to reset scopes or tweak the package list on
+// resubmission.
+func (c *Client) RetryJob(ctx context.Context, id string, opts RetryOptions) (string, error) {
+	client := utility.GetDefaultHTTPRetryableClient()
+	defer utility.PutHTTPClient(client)
+
+	payload, err := json.Marshal(retryOptionsPayload{ResetScopes: opts.ResetScopes, Overrides: opts.Overrides})
+	if err != nil {
+		return "", errors.Wrap(err, "problem marshaling retry options")
+	}
+
+	req, err := c.makeRequest(ctx, strings.Join([]string{"repobuilder", id, "retry"}, "/"), http.MethodPost, bytes.NewBuffer(payload))
+	if err != nil {
+		return "", errors.Wrap(err, "problem building retry request")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "problem making retry request")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", c.handleError(resp.StatusCode, resp.Body)
+	}
+
+	out := struct {
+		ID string `json:"id"`
+	}{}
+	if err = gimlet.GetJSON(resp.Body, &out); err != nil {
+		return "", errors.Wrap(err, "problem reading body of retry response")
+	}
+
+	return out.ID, nil
+}
+
+type jobPriorityPayload struct {
+	Priority int `json:"priority"`
+}
+
+// SetJobPriority bumps (or lowers) id's priority in barque's amboy
+// queue, e.g. to move a hotfix rebuild ahead of routine traffic without
+// canceling and resubmitting it.
+func (c *Client) SetJobPriority(ctx context.Context, id string, priority int) error {
+	client := utility.GetDefaultHTTPRetryableClient()
+	defer utility.PutHTTPClient(client)
+
+	payload, err := json.Marshal(jobPriorityPayload{Priority: priority})
+	if err != nil {
+		return errors.Wrap(err, "problem marshaling job priority payload")
+	}
+
+	req, err := c.makeRequest(ctx, strings.Join([]string{"repobuilder", id}, "/"), http.MethodPatch, bytes.NewBuffer(payload))
+	if err != nil {
+		return errors.Wrap(err, "problem building priority request")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "problem making priority request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return c.handleError(resp.StatusCode, resp.Body)
+	}
+
+	return nil
+}
+
+// WaitJob blocks until id reaches a terminal state, long-polling
+// repobuilder/wait/{id} so the server can hang the connection open
+// until the job's status actually changes instead of the caller
+// spinning on CheckJobStatus. It backs off between attempts that come
+// back without a change (including transient request errors), and
+// returns promptly if ctx is canceled.
+func (c *Client) WaitJob(ctx context.Context, id string, opts WaitJobOptions) (*JobStatus, error) {
+	bo := opts.backoff()
+	since := ""
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, errors.Wrap(err, "context canceled while waiting for job")
+		}
+
+		status, changed, err := c.longPollJobStatus(ctx, id, since)
+		if err != nil {
+			grip.Warningln(err, "retrying long-poll for job", id)
+			if sleepErr := sleepOrCancel(ctx, bo.Duration()); sleepErr != nil {
+				return nil, errors.Wrap(sleepErr, "context canceled while waiting for job")
+			}
+			continue
+		}
+
+		if !changed {
+			if sleepErr := sleepOrCancel(ctx, bo.Duration()); sleepErr != nil {
+				return nil, errors.Wrap(sleepErr, "context canceled while waiting for job")
+			}
+			continue
+		}
+
+		bo.Reset()
+		since = time.Now().UTC().Format(time.RFC3339Nano)
+
+		if status.Status.Complete {
+			return status, nil
+		}
+	}
+}
+
+// longPollJobStatus makes a single request to repobuilder/wait/{id},
+// passing since along so the server only returns once the job's
+// status has changed since then (or its own deadline elapses). A 304
+// response means the deadline elapsed with no change; changed is
+// false and status is nil in that case.
+func (c *Client) longPollJobStatus(ctx context.Context, id, since string) (status *JobStatus, changed bool, err error) {
+	client := utility.GetDefaultHTTPRetryableClient()
+	defer utility.PutHTTPClient(client)
+
+	url := strings.Join([]string{"repobuilder", "wait", id}, "/")
+	if since != "" {
+		url += "?since=" + since
+	}
+
+	req, err := c.makeRequest(ctx, url, http.MethodGet, nil)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "problem building wait request")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "problem making wait request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, false, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, c.handleError(resp.StatusCode, resp.Body)
+	}
+
+	out := &JobStatus{}
+	if err = gimlet.GetJSON(resp.Body, out); err != nil {
+		return nil, false, errors.Wrap(err, "problem reading body of wait response")
+	}
+
+	return out, true, nil
+}
+
+// StreamJobs returns a channel of JobStatus updates for every job in
+// ids. It first tries a single SSE subscription at repobuilder/events
+// (reconnecting with Last-Event-ID on disconnect), and falls back to
+// one long-poll loop per id when the server doesn't support SSE. The
+// channel is closed once every id has reached a terminal state or ctx
+// is canceled.
+func (c *Client) StreamJobs(ctx context.Context, ids []string) (<-chan JobStatus, error) {
+	out := make(chan JobStatus, len(ids))
+
+	body, err := c.openEventStream(ctx, ids, "")
+	if err == nil {
+		go c.streamSSE(ctx, ids, body, out)
+		return out, nil
+	}
+
+	grip.Debugln("barque server does not support SSE, falling back to per-job long-poll:", err)
+
+	go c.streamLongPoll(ctx, ids, out)
+	return out, nil
+}
+
+// pooledClientBody wraps an SSE response body so that closing it also
+// releases the pooled *http.Client that produced it back to utility's
+// pool. Without this, streamSSE's reconnect loop -- which calls
+// openEventStream again on every dropped connection -- would leak one
+// pooled client per reconnect.
+type pooledClientBody struct {
+	io.ReadCloser
+	client *http.Client
+}
+
+func (b *pooledClientBody) Close() error {
+	err := b.ReadCloser.Close()
+	utility.PutHTTPClient(b.client)
+	return err
+}
+
+// openEventStream opens the SSE subscription at repobuilder/events,
+// resuming from lastEventID when reconnecting. It returns an error
+// (and closes the response) if the server doesn't answer with a
+// text/event-stream body, so callers can fall back to long-polling.
+func (c *Client) openEventStream(ctx context.Context, ids []string, lastEventID string) (io.ReadCloser, error) {
+	client := utility.GetDefaultHTTPRetryableClient()
+
+	url := "repobuilder/events"
+	if len(ids) > 0 {
+		url += "?ids=" + strings.Join(ids, ",")
+	}
+
+	req, err := c.makeRequest(ctx, url, http.MethodGet, nil)
+	if err != nil {
+		utility.PutHTTPClient(client)
+		return nil, errors.Wrap(err, "problem building event stream request")
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		utility.PutHTTPClient(client)
+		return nil, errors.Wrap(err, "problem opening event stream")
+	}
+
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		resp.Body.Close()
+		utility.PutHTTPClient(client)
+		return nil, errors.Errorf("barque server does not support SSE (status %d, content-type %q)",
+			resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	return &pooledClientBody{ReadCloser: resp.Body, client: client}, nil
+}
+
+// streamSSE reads JobStatus frames off body until it's exhausted or
+// errors, then reconnects (with backoff) using the last "id:" field it
+// saw, until every id in ids is complete or ctx is canceled.
+func (c *Client) streamSSE(ctx context.Context, ids []string, body io.ReadCloser, out chan JobStatus) {
+	defer close(out)
+
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	bo := NewDefaultWaitJobOptions().backoff()
+	lastEventID := ""
+
+	for {
+		lastEventID = c.readEventStream(ctx, body, wanted, out, lastEventID)
+		body.Close()
+
+		if ctx.Err() != nil || len(wanted) == 0 {
+			return
+		}
+
+		grip.Warningln("event stream disconnected, reconnecting with Last-Event-ID", lastEventID)
+		if sleepErr := sleepOrCancel(ctx, bo.Duration()); sleepErr != nil {
+			return
+		}
+
+		var err error
+		body, err = c.openEventStream(ctx, ids, lastEventID)
+		for err != nil {
+			grip.Error(errors.Wrap(err, "reconnecting event stream"))
+			if sleepErr := sleepOrCancel(ctx, bo.Duration()); sleepErr != nil {
+				return
+			}
+			body, err = c.openEventStream(ctx, ids, lastEventID)
+		}
+	}
+}
+
+// readEventStream parses "data:"/"id:" SSE lines out of body until EOF
+// or ctx is canceled, decoding each frame's data as a JobStatus and
+// emitting it on out when its ID is in wanted. It returns the last
+// "id:" value seen, for use as Last-Event-ID on reconnect.
+func (c *Client) readEventStream(ctx context.Context, body io.Reader, wanted map[string]bool, out chan JobStatus, lastEventID string) string {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var data strings.Builder
+
+	flush := func() {
+		if data.Len() == 0 {
+			return
+		}
+		defer data.Reset()
+
+		status := JobStatus{}
+		if err := json.Unmarshal([]byte(data.String()), &status); err != nil {
+			grip.Error(errors.Wrap(err, "decoding SSE job status frame"))
+			return
+		}
+
+		if !wanted[status.ID] {
+			return
+		}
+
+		select {
+		case out <- status:
+		case <-ctx.Done():
+			return
+		}
+
+		if status.Status.Complete {
+			delete(wanted, status.ID)
+		}
+	}
+
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return lastEventID
+		}
+
+		line := scanner.Text()
+		switch {
+		case line == "":
+			flush()
+		case strings.HasPrefix(line, "id:"):
+			lastEventID = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		}
+	}
+	flush()
+
+	return lastEventID
+}
+
+// streamLongPoll is StreamJobs' fallback when the server doesn't
+// support SSE: it runs one WaitJob-style long-poll loop per id,
+// concurrently, forwarding every status change (not just the
+// terminal one) onto out.
+func (c *Client) streamLongPoll(ctx context.Context, ids []string, out chan JobStatus) {
+	defer close(out)
+
+	opts := NewDefaultWaitJobOptions()
+
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+
+		go func(id string) {
+			defer wg.Done()
+
+			bo := opts.backoff()
+			since := ""
+
+			for {
+				if ctx.Err() != nil {
+					return
+				}
+
+				status, changed, err := c.longPollJobStatus(ctx, id, since)
+				if err != nil {
+					grip.Warningln(err, "retrying long-poll for job", id)
+					if sleepErr := sleepOrCancel(ctx, bo.Duration()); sleepErr != nil {
+						return
+					}
+					continue
+				}
+
+				if !changed {
+					if sleepErr := sleepOrCancel(ctx, bo.Duration()); sleepErr != nil {
+						return
+					}
+					continue
+				}
+
+				bo.Reset()
+				since = time.Now().UTC().Format(time.RFC3339Nano)
+
+				select {
+				case out <- *status:
+				case <-ctx.Done():
+					return
+				}
+
+				if status.Status.Complete {
+					return
+				}
+			}
+		}(id)
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,79 @@
+package repobuilder
+
+import (
+	"context"
+
+	"github.com/mongodb/curator/sthree"
+	"github.com/pkg/errors"
+)
+
+// BackendType identifies which object-store implementation a
+// RepositoryDefinition's repos should be synced through.
+type BackendType string
+
+const (
+	BackendS3    BackendType = "s3"
+	BackendGCS   BackendType = "gcs"
+	BackendAzure BackendType = "azure"
+	BackendLocal BackendType = "local"
+)
+
+// Backend abstracts the object-store operations that Job.Run needs in
+// order to mirror a repository, so the same link -> inject ->
+// rebuildRepo -> sync pipeline can target S3, Google Cloud Storage,
+// Azure Blob Storage, or a local filesystem mirror interchangeably.
+type Backend interface {
+	// Open establishes the backend's connection, or, for local
+	// mirrors, creates the destination directory if it doesn't exist.
+	Open(ctx context.Context) error
+
+	// Close releases any resources acquired by Open.
+	Close()
+
+	// Clone returns an independent copy of the Backend pointed at
+	// the same destination, for use from a separate goroutine.
+	Clone(ctx context.Context) (Backend, error)
+
+	// DryRunClone returns a copy of the Backend that logs writes
+	// instead of performing them.
+	DryRunClone(ctx context.Context) (Backend, error)
+
+	// SyncFrom mirrors the contents of prefix in the backend down
+	// to local.
+	SyncFrom(ctx context.Context, local, prefix string, opts sthree.SyncOptions) error
+
+	// SyncTo mirrors the contents of local up to prefix in the
+	// backend.
+	SyncTo(ctx context.Context, local, prefix string, opts sthree.SyncOptions) error
+
+	// SetNewFilePermission sets the permission applied to objects
+	// written by SyncTo.
+	SetNewFilePermission(sthree.ObjectPermission)
+
+	// String returns a human readable identifier for the backend,
+	// for logging.
+	String() string
+}
+
+// openBackend constructs and opens the Backend configured by distro,
+// defaulting to the historical S3-via-sthree behavior when no
+// BackendType is set, so existing RepositoryDefinitions don't need to
+// be migrated.
+func openBackend(ctx context.Context, distro *RepositoryDefinition, profile string) (Backend, error) {
+	var backend Backend
+
+	switch distro.BackendType {
+	case "", BackendS3:
+		backend = newS3Backend(sthree.GetBucketWithProfile(distro.Bucket, profile))
+	case BackendGCS:
+		backend = newGCSBackend(distro.Bucket, distro.BackendConfig)
+	case BackendAzure:
+		backend = newAzureBackend(distro.Bucket, distro.BackendConfig)
+	case BackendLocal:
+		backend = newLocalBackend(distro.Bucket, distro.BackendConfig)
+	default:
+		return nil, errors.Errorf("unrecognized backend type '%s'", distro.BackendType)
+	}
+
+	return backend, errors.Wrap(backend.Open(ctx), "opening backend")
+}
@@ -0,0 +1,151 @@
+package repobuilder
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/mongodb/curator/sthree"
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+)
+
+// azureBackend adapts an Azure Blob Storage container to the Backend
+// interface, for mirroring repositories into private Azure-backed
+// registries.
+type azureBackend struct {
+	accountURL    string
+	containerName string
+	dryRun        bool
+	permission    sthree.ObjectPermission
+	client        *container.Client
+}
+
+// newAzureBackend constructs an azureBackend for the named container.
+// config["account_url"] is the https://<account>.blob.core.windows.net
+// endpoint; authentication is picked up from the ambient environment
+// (DefaultAzureCredential) the same way the GCS backend defers to its
+// client library's default credential chain.
+func newAzureBackend(containerName string, config map[string]string) *azureBackend {
+	return &azureBackend{accountURL: config["account_url"], containerName: containerName}
+}
+
+func (b *azureBackend) Open(ctx context.Context) error {
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return errors.Wrap(err, "resolving default Azure credential")
+	}
+
+	client, err := container.NewClient(b.accountURL+"/"+b.containerName, cred, nil)
+	if err != nil {
+		return errors.Wrap(err, "creating Azure Blob client")
+	}
+
+	b.client = client
+	return nil
+}
+
+func (b *azureBackend) Close() {}
+
+func (b *azureBackend) Clone(ctx context.Context) (Backend, error) {
+	clone := &azureBackend{accountURL: b.accountURL, containerName: b.containerName, dryRun: b.dryRun, permission: b.permission}
+	return clone, clone.Open(ctx)
+}
+
+func (b *azureBackend) DryRunClone(ctx context.Context) (Backend, error) {
+	clone, err := b.Clone(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	clone.(*azureBackend).dryRun = true
+	return clone, nil
+}
+
+func (b *azureBackend) SetNewFilePermission(perm sthree.ObjectPermission) {
+	// Azure Blob containers grant public read at the container
+	// level rather than per-blob, so this is a no-op here; the
+	// container access policy is expected to be configured
+	// out-of-band to match perm.
+	b.permission = perm
+}
+
+func (b *azureBackend) String() string {
+	return b.containerName
+}
+
+func (b *azureBackend) SyncFrom(ctx context.Context, local, prefix string, opts sthree.SyncOptions) error {
+	catcher := grip.NewCatcher()
+	pager := b.client.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{Prefix: &prefix})
+
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return errors.Wrap(err, "listing Azure blobs")
+		}
+
+		for _, blob := range page.Segment.BlobItems {
+			name := *blob.Name
+			dest := filepath.Join(local, name[len(prefix):])
+			if err = b.downloadBlob(ctx, name, dest); err != nil {
+				catcher.Add(errors.Wrapf(err, "downloading %s", name))
+			}
+		}
+	}
+
+	return catcher.Resolve()
+}
+
+func (b *azureBackend) downloadBlob(ctx context.Context, name, dest string) error {
+	resp, err := b.client.NewBlobClient(name).DownloadStream(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(dest, data, 0644)
+}
+
+func (b *azureBackend) SyncTo(ctx context.Context, local, prefix string, opts sthree.SyncOptions) error {
+	catcher := grip.NewCatcher()
+
+	catcher.Add(filepath.Walk(local, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		blobName := strings.TrimPrefix(filepath.Join(prefix, path[len(local)+1:]), "/")
+
+		if b.dryRun {
+			grip.Infof("dry-run: would upload %s -> %s/%s", path, b.containerName, blobName)
+			return nil
+		}
+
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "reading %s", path)
+		}
+
+		_, err = b.client.NewBlockBlobClient(blobName).UploadBuffer(ctx, contents, &azblob.UploadBufferOptions{})
+		return errors.Wrapf(err, "uploading %s", blobName)
+	}))
+
+	return catcher.Resolve()
+}
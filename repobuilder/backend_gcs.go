@@ -0,0 +1,158 @@
+package repobuilder
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/mongodb/curator/sthree"
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+	"google.golang.org/api/iterator"
+)
+
+// gcsBackend adapts a Google Cloud Storage bucket to the Backend
+// interface, for mirroring repositories into private GCS-backed
+// registries.
+type gcsBackend struct {
+	bucketName string
+	dryRun     bool
+	permission sthree.ObjectPermission
+	client     *storage.Client
+}
+
+// newGCSBackend constructs a gcsBackend for the named bucket. config
+// is reserved for future per-backend options (e.g. a service account
+// key path) and is currently unused beyond the defaults picked up
+// from the ambient environment by the GCS client library.
+func newGCSBackend(bucketName string, config map[string]string) *gcsBackend {
+	return &gcsBackend{bucketName: bucketName}
+}
+
+func (b *gcsBackend) Open(ctx context.Context) error {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return errors.Wrap(err, "creating GCS client")
+	}
+
+	b.client = client
+	return nil
+}
+
+func (b *gcsBackend) Close() {
+	if b.client != nil {
+		grip.CatchError(b.client.Close())
+	}
+}
+
+func (b *gcsBackend) Clone(ctx context.Context) (Backend, error) {
+	clone := &gcsBackend{bucketName: b.bucketName, dryRun: b.dryRun, permission: b.permission}
+	return clone, clone.Open(ctx)
+}
+
+func (b *gcsBackend) DryRunClone(ctx context.Context) (Backend, error) {
+	clone, err := b.Clone(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	clone.(*gcsBackend).dryRun = true
+	return clone, nil
+}
+
+func (b *gcsBackend) SetNewFilePermission(perm sthree.ObjectPermission) {
+	b.permission = perm
+}
+
+func (b *gcsBackend) String() string {
+	return b.bucketName
+}
+
+func (b *gcsBackend) SyncFrom(ctx context.Context, local, prefix string, opts sthree.SyncOptions) error {
+	bucket := b.client.Bucket(b.bucketName)
+	it := bucket.Objects(ctx, &storage.Query{Prefix: prefix})
+
+	catcher := grip.NewCatcher()
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "listing GCS objects")
+		}
+
+		dest := filepath.Join(local, attrs.Name[len(prefix):])
+		if err = b.downloadObject(ctx, bucket, attrs.Name, dest); err != nil {
+			catcher.Add(errors.Wrapf(err, "downloading %s", attrs.Name))
+		}
+	}
+
+	return catcher.Resolve()
+}
+
+func (b *gcsBackend) downloadObject(ctx context.Context, bucket *storage.BucketHandle, name, dest string) error {
+	reader, err := bucket.Object(name).NewReader(ctx)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(dest, data, 0644)
+}
+
+func (b *gcsBackend) SyncTo(ctx context.Context, local, prefix string, opts sthree.SyncOptions) error {
+	bucket := b.client.Bucket(b.bucketName)
+	catcher := grip.NewCatcher()
+
+	catcher.Add(filepath.Walk(local, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		objectName := strings.TrimPrefix(filepath.Join(prefix, path[len(local)+1:]), "/")
+
+		if b.dryRun {
+			grip.Infof("dry-run: would upload %s -> gs://%s/%s", path, b.bucketName, objectName)
+			return nil
+		}
+
+		return b.uploadObject(ctx, bucket, path, objectName)
+	}))
+
+	return catcher.Resolve()
+}
+
+func (b *gcsBackend) uploadObject(ctx context.Context, bucket *storage.BucketHandle, path, objectName string) error {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "reading %s", path)
+	}
+
+	writer := bucket.Object(objectName).NewWriter(ctx)
+	if b.permission == sthree.PermissionPublicRead {
+		writer.PredefinedACL = "publicRead"
+	}
+
+	if _, err = writer.Write(contents); err != nil {
+		grip.CatchError(writer.Close())
+		return errors.Wrapf(err, "uploading %s", objectName)
+	}
+
+	return errors.Wrapf(writer.Close(), "finalizing upload of %s", objectName)
+}
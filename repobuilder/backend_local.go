@@ -0,0 +1,111 @@
+package repobuilder
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/mongodb/curator/sthree"
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+)
+
+// localBackend mirrors a repository to a directory on the local
+// filesystem. It's useful for air-gapped mirrors that have no object
+// store at all, and for exercising the repobuilder pipeline without
+// network access.
+type localBackend struct {
+	root       string
+	dryRun     bool
+	permission sthree.ObjectPermission
+}
+
+// newLocalBackend constructs a localBackend rooted at config["root"],
+// falling back to name (the RepositoryDefinition's Bucket field) when
+// no root is configured.
+func newLocalBackend(name string, config map[string]string) *localBackend {
+	root := config["root"]
+	if root == "" {
+		root = name
+	}
+
+	return &localBackend{root: root}
+}
+
+func (b *localBackend) Open(ctx context.Context) error {
+	return errors.Wrapf(os.MkdirAll(b.root, 0755), "creating local backend root %s", b.root)
+}
+
+func (b *localBackend) Close() {}
+
+func (b *localBackend) Clone(ctx context.Context) (Backend, error) {
+	return &localBackend{root: b.root, dryRun: b.dryRun, permission: b.permission}, nil
+}
+
+func (b *localBackend) DryRunClone(ctx context.Context) (Backend, error) {
+	clone, err := b.Clone(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	clone.(*localBackend).dryRun = true
+	return clone, nil
+}
+
+func (b *localBackend) SetNewFilePermission(perm sthree.ObjectPermission) {
+	b.permission = perm
+}
+
+func (b *localBackend) String() string {
+	return b.root
+}
+
+func (b *localBackend) SyncFrom(ctx context.Context, local, prefix string, opts sthree.SyncOptions) error {
+	return errors.Wrap(b.copyTree(filepath.Join(b.root, prefix), local), "sync from local backend")
+}
+
+func (b *localBackend) SyncTo(ctx context.Context, local, prefix string, opts sthree.SyncOptions) error {
+	if b.dryRun {
+		grip.Infof("dry-run: would copy %s -> %s/%s", local, b.root, prefix)
+		return nil
+	}
+
+	return errors.Wrap(b.copyTree(local, filepath.Join(b.root, prefix)), "sync to local backend")
+}
+
+// copyTree recursively copies src to dst, creating directories as
+// needed, mirroring the semantics sthree.Bucket's sync jobs apply
+// against S3.
+func (b *localBackend) copyTree(src, dst string) error {
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return nil
+	}
+
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return errors.Wrapf(err, "resolving relative path for %s", path)
+		}
+		target := filepath.Join(dst, rel)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "reading %s", path)
+		}
+
+		if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return errors.Wrapf(err, "creating directory for %s", target)
+		}
+
+		return errors.Wrapf(ioutil.WriteFile(target, contents, 0644), "writing %s", target)
+	})
+}
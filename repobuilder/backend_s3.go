@@ -0,0 +1,35 @@
+package repobuilder
+
+import (
+	"context"
+
+	"github.com/mongodb/curator/sthree"
+)
+
+// s3Backend adapts *sthree.Bucket, curator's original and
+// longest-lived object-store client, to the Backend interface.
+type s3Backend struct {
+	*sthree.Bucket
+}
+
+func newS3Backend(bucket *sthree.Bucket) *s3Backend {
+	return &s3Backend{Bucket: bucket}
+}
+
+func (b *s3Backend) Clone(ctx context.Context) (Backend, error) {
+	clone, err := b.Bucket.Clone(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &s3Backend{Bucket: clone}, nil
+}
+
+func (b *s3Backend) DryRunClone(ctx context.Context) (Backend, error) {
+	clone, err := b.Bucket.DryRunClone(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &s3Backend{Bucket: clone}, nil
+}
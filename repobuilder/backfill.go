@@ -0,0 +1,240 @@
+package repobuilder
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mongodb/amboy/queue"
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+)
+
+// BackfillOutcome describes what happened to a single (series, arch,
+// distro) combination during a backfill run.
+type BackfillOutcome string
+
+const (
+	BackfillSuccess BackfillOutcome = "success"
+	BackfillSkipped BackfillOutcome = "skipped"
+	BackfillError   BackfillOutcome = "error"
+)
+
+// BackfillKey identifies a single unit of backfill work in a
+// BackfillReport.
+type BackfillKey struct {
+	Series string
+	Arch   string
+	Distro string
+}
+
+func (k BackfillKey) String() string {
+	return fmt.Sprintf("%s/%s/%s", k.Distro, k.Series, k.Arch)
+}
+
+// BackfillReport maps every (series, arch, distro) combination a
+// BackfillPlanner considered to its outcome.
+type BackfillReport map[BackfillKey]BackfillOutcome
+
+// BackfillSpec describes a bulk historical rebuild: every release
+// series in VersionRange (inclusive, e.g. "3.0..5.0"), across every
+// entry in Archs and Distros, is rebuilt in one invocation.
+type BackfillSpec struct {
+	Conf           *RepositoryConfig
+	Distros        []*RepositoryDefinition
+	Archs          []string
+	VersionRange   string
+	IncludeRC      bool
+	IncludeNightly bool
+	Profile        string
+	PackagePaths   []string
+
+	// MaxParallel bounds the number of rebuild jobs that run at
+	// once. A value <= 0 defaults to 1 (fully serial), which is
+	// the safest default for a bulk operation that fans out
+	// against a shared notary service and object store.
+	MaxParallel int
+}
+
+// BackfillPlanner enumerates every (series, arch, distro) combination
+// described by a BackfillSpec, deduplicates by the target each Job
+// would write to, and drives the resulting Jobs through a bounded
+// amboy queue.
+type BackfillPlanner struct {
+	spec BackfillSpec
+}
+
+// NewBackfillPlanner constructs a BackfillPlanner for spec.
+func NewBackfillPlanner(spec BackfillSpec) *BackfillPlanner {
+	return &BackfillPlanner{spec: spec}
+}
+
+// Run enumerates the cartesian product of series x arch x distro,
+// submits one *Job per unique getPackageLocation() target to a bounded
+// amboy queue, and returns a report of what happened to every
+// combination considered, including those skipped as duplicates.
+func (p *BackfillPlanner) Run(ctx context.Context) (BackfillReport, error) {
+	series, err := expandVersionRange(p.spec.VersionRange, p.spec.IncludeRC, p.spec.IncludeNightly)
+	if err != nil {
+		return nil, errors.Wrapf(err, "expanding version range %s", p.spec.VersionRange)
+	}
+
+	maxParallel := p.spec.MaxParallel
+	if maxParallel <= 0 {
+		maxParallel = 1
+	}
+
+	q := queue.NewLocalUnordered(maxParallel)
+	if err = q.Start(ctx); err != nil {
+		return nil, errors.Wrap(err, "starting backfill queue")
+	}
+	defer q.Close()
+
+	report := make(BackfillReport)
+	seenTargets := make(map[string]BackfillKey)
+	mutex := &sync.Mutex{}
+	catcher := grip.NewCatcher()
+
+	for _, distro := range p.spec.Distros {
+		for _, arch := range p.spec.Archs {
+			for _, version := range series {
+				key := BackfillKey{Series: version, Arch: arch, Distro: distro.Bucket}
+
+				j, err := NewBuildRepoJob(p.spec.Conf, distro, version, arch, p.spec.Profile, p.spec.PackagePaths...)
+				if err != nil {
+					catcher.Add(errors.Wrapf(err, "building job for %s", key))
+					mutex.Lock()
+					report[key] = BackfillError
+					mutex.Unlock()
+					continue
+				}
+
+				target := j.getPackageLocation() + "/" + distro.Bucket + "/" + arch
+
+				mutex.Lock()
+				if existing, ok := seenTargets[target]; ok {
+					grip.Infof("skipping %s: already covered by %s", key, existing)
+					report[key] = BackfillSkipped
+					mutex.Unlock()
+					continue
+				}
+				seenTargets[target] = key
+				mutex.Unlock()
+
+				if err = q.Put(j); err != nil {
+					catcher.Add(errors.Wrapf(err, "queueing job for %s", key))
+					mutex.Lock()
+					report[key] = BackfillError
+					mutex.Unlock()
+					continue
+				}
+			}
+		}
+	}
+
+	q.Wait()
+
+	for result := range q.Results() {
+		job, ok := result.(*Job)
+		if !ok {
+			continue
+		}
+
+		key := BackfillKey{Series: job.Version, Arch: job.Arch, Distro: job.Distro.Bucket}
+
+		if job.HasErrors() {
+			catcher.Add(errors.Wrapf(job.Error(), "backfill job %s", key))
+			report[key] = BackfillError
+		} else {
+			report[key] = BackfillSuccess
+		}
+	}
+
+	grip.Noticef("backfill complete: %d combinations considered", len(report))
+
+	return report, catcher.Resolve()
+}
+
+// maxMinorPerMajor bounds how far expandVersionRange walks the minor
+// component for a major version that isn't the range's final one: we
+// have no table of how many minors each major actually shipped, so we
+// walk every minor up to this ceiling before carrying into the next
+// major.
+const maxMinorPerMajor = 9
+
+// expandVersionRange turns a "<start>..<end>" series range into the
+// list of release series between start and end, inclusive, stepping
+// by minor version and carrying into the next major once a major's
+// minors are exhausted. includeRC and includeNightly append the
+// well-known "testing" and "development" pseudo-series respectively,
+// matching the targets Job.getPackageLocation() resolves release
+// candidates and nightlies to.
+func expandVersionRange(r string, includeRC, includeNightly bool) ([]string, error) {
+	parts := strings.SplitN(r, "..", 2)
+	if len(parts) != 2 {
+		return nil, errors.Errorf("version range '%s' is not of the form '<start>..<end>'", r)
+	}
+
+	start, err := parseSeries(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseSeries(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	if start[0] > end[0] || (start[0] == end[0] && start[1] > end[1]) {
+		return nil, errors.Errorf("version range '%s' has start after end", r)
+	}
+
+	var series []string
+	for major := start[0]; major <= end[0]; major++ {
+		minor := 0
+		if major == start[0] {
+			minor = start[1]
+		}
+
+		maxMinor := maxMinorPerMajor
+		if major == end[0] {
+			maxMinor = end[1]
+		}
+
+		for ; minor <= maxMinor; minor++ {
+			series = append(series, fmt.Sprintf("%d.%d", major, minor))
+		}
+	}
+
+	if includeRC {
+		series = append(series, "testing")
+	}
+	if includeNightly {
+		series = append(series, "development")
+	}
+
+	return series, nil
+}
+
+func parseSeries(s string) ([2]int, error) {
+	var out [2]int
+
+	pieces := strings.SplitN(strings.TrimSpace(s), ".", 2)
+	if len(pieces) != 2 {
+		return out, errors.Errorf("series '%s' is not of the form '<major>.<minor>'", s)
+	}
+
+	major, err := strconv.Atoi(pieces[0])
+	if err != nil {
+		return out, errors.Wrapf(err, "parsing major version from '%s'", s)
+	}
+
+	minor, err := strconv.Atoi(pieces[1])
+	if err != nil {
+		return out, errors.Wrapf(err, "parsing minor version from '%s'", s)
+	}
+
+	out[0], out[1] = major, minor
+	return out, nil
+}
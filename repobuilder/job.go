@@ -10,12 +10,12 @@ import (
 	"sync"
 	"time"
 
-	"github.com/goamz/goamz/s3"
 	"github.com/mongodb/amboy"
 	"github.com/mongodb/amboy/dependency"
 	"github.com/mongodb/amboy/job"
 	"github.com/mongodb/amboy/registry"
 	"github.com/mongodb/curator"
+	"github.com/mongodb/curator/repobuilder/notary"
 	"github.com/mongodb/curator/sthree"
 	"github.com/mongodb/grip"
 	"github.com/pkg/errors"
@@ -28,21 +28,23 @@ type jobImpl interface {
 
 // Job provides the common structure for a repository building Job.
 type Job struct {
-	Distro       *RepositoryDefinition `bson:"distro" json:"distro" yaml:"distro"`
-	Conf         *RepositoryConfig     `bson:"conf" json:"conf" yaml:"conf"`
-	DryRun       bool                  `bson:"dry_run" json:"dry_run" yaml:"dry_run"`
-	Output       map[string]string     `bson:"output" json:"output" yaml:"output"`
-	Version      string                `bson:"version" json:"version" yaml:"version"`
-	Arch         string                `bson:"arch" json:"arch" yaml:"arch"`
-	Profile      string                `bson:"aws_profile" json:"aws_profile" yaml:"aws_profile"`
-	WorkSpace    string                `bson:"local_workdir" json:"local_workdir" yaml:"local_workdir"`
-	PackagePaths []string              `bson:"package_paths" json:"package_paths" yaml:"package_paths"`
-	*job.Base    `bson:"metadata" json:"metadata" yaml:"metadata"`
+	Distro                *RepositoryDefinition `bson:"distro" json:"distro" yaml:"distro"`
+	Conf                  *RepositoryConfig     `bson:"conf" json:"conf" yaml:"conf"`
+	DryRun                bool                  `bson:"dry_run" json:"dry_run" yaml:"dry_run"`
+	Output                map[string]string     `bson:"output" json:"output" yaml:"output"`
+	Version               string                `bson:"version" json:"version" yaml:"version"`
+	Arch                  string                `bson:"arch" json:"arch" yaml:"arch"`
+	Profile               string                `bson:"aws_profile" json:"aws_profile" yaml:"aws_profile"`
+	WorkSpace             string                `bson:"local_workdir" json:"local_workdir" yaml:"local_workdir"`
+	PackagePaths          []string              `bson:"package_paths" json:"package_paths" yaml:"package_paths"`
+	UseLegacyNotaryClient bool                  `bson:"use_legacy_notary_client" json:"use_legacy_notary_client" yaml:"use_legacy_notary_client"`
+	*job.Base             `bson:"metadata" json:"metadata" yaml:"metadata"`
 
 	workingDirs []string
 	release     *curator.MongoDBVersion
 	mutex       sync.RWMutex
 	builder     jobImpl
+	resources   *resourceController
 }
 
 func init() {
@@ -77,6 +79,8 @@ func NewBuildRepoJob(conf *RepositoryConfig, distro *RepositoryDefinition, versi
 		setupDEBJob(j)
 	} else if distro.Type == RPM {
 		setupRPMJob(j)
+	} else if distro.Type == OCI {
+		setupOCIJob(j)
 	}
 
 	j.release, err = curator.NewMongoDBVersion(version)
@@ -98,6 +102,7 @@ func NewBuildRepoJob(conf *RepositoryConfig, distro *RepositoryDefinition, versi
 	j.PackagePaths = pkgs
 	j.Version = version
 	j.Profile = profile
+	j.resources = controllerForConfig(conf)
 
 	return j, nil
 }
@@ -139,10 +144,17 @@ func (j *Job) linkPackages(dest string) error {
 			if j.Distro.Type == RPM {
 				wg.Add(1)
 				go func(toSign string) {
+					defer wg.Done()
+
+					if err := j.resourceController().acquireSigningSlot(context.Background()); err != nil {
+						catcher.Add(errors.Wrapf(err, "acquiring signing slot for %s", toSign))
+						return
+					}
+					defer j.resourceController().releaseSigningSlot()
+
 					// sign each package, overwriting the package with the signed package.
 					catcher.Add(errors.Wrapf(j.signFile(toSign, "", true), // (name, extension, overwrite)
 						"problem signing file %s", toSign))
-					wg.Done()
 				}(mirror)
 			}
 
@@ -154,6 +166,17 @@ func (j *Job) linkPackages(dest string) error {
 	return catcher.Resolve()
 }
 
+// resourceController returns the resourceController enforcing this
+// Job's ResourceLimits, lazily constructing one for Jobs built outside
+// of NewBuildRepoJob (e.g. deserialized off of an amboy queue).
+func (j *Job) resourceController() *resourceController {
+	if j.resources == nil {
+		j.resources = controllerForConfig(j.Conf)
+	}
+
+	return j.resources
+}
+
 func (j *Job) injectNewPackages(local string) (string, error) {
 	return j.builder.injectPackage(local, j.getPackageLocation())
 }
@@ -171,18 +194,19 @@ func (j *Job) getPackageLocation() string {
 	}
 }
 
-// signFile wraps the python notary-client.py script. Pass it the name
+// signFile signs fileName with the notary service. Pass it the name
 // of a file to sign, the "archiveExtension" (which only impacts
 // non-package files, as defined by the notary service and client,)
 // and an "overwrite" bool. Overwrite: forces package signing to
 // overwrite the existing file, removing the archive's
 // signature. Using overwrite=true and a non-nil string is not logical
 // and returns a warning, but is passed to the client.
+//
+// By default this talks to the notary service directly over its REST
+// API via the notary package. Setting Job.UseLegacyNotaryClient
+// retains the old behavior of shelling out to notary-client.py, as an
+// escape hatch while the native client proves itself out.
 func (j *Job) signFile(fileName, archiveExtension string, overwrite bool) error {
-	// In the future it would be nice if we could talk to the
-	// notary service directly rather than shelling out here. The
-	// final option controls if we overwrite this file.
-
 	var keyName string
 	var token string
 
@@ -199,16 +223,6 @@ func (j *Job) signFile(fileName, archiveExtension string, overwrite bool) error
 			"(NOTARY_TOKEN) is not defined in the environment"))
 	}
 
-	args := []string{
-		"notary-client.py",
-		"--key-name", keyName,
-		"--auth-token", token,
-		"--comment", "\"curator package signing\"",
-		"--notary-url", j.Conf.Services.NotaryURL,
-		"--archive-file-ext", archiveExtension,
-		"--outputs", "sig",
-	}
-
 	grip.AlertWhenf(strings.HasPrefix(archiveExtension, "."),
 		"extension '%s', has a leading dot, which is almost certainly undesirable.", archiveExtension)
 
@@ -216,15 +230,56 @@ func (j *Job) signFile(fileName, archiveExtension string, overwrite bool) error
 		"specified overwrite with an archive extension:", archiveExtension,
 		"this is probably an error, (not impacting packages,) but is passed to the client.")
 
-	if overwrite {
-		grip.Noticef("overwriting existing contents of file '%s' while signing it", fileName)
-		args = append(args, "--package-file-suffix", "")
-	} else {
+	packageFileSuffix := ""
+	if !overwrite {
+		packageFileSuffix = "." + archiveExtension
+
 		// if we're not overwriting the unsigned source file
 		// with the signed file, then we should remove the
 		// signed artifact before. Unclear if this is needed,
 		// the cronjob did this.
 		grip.CatchWarning(os.Remove(fileName + "." + archiveExtension))
+	} else {
+		grip.Noticef("overwriting existing contents of file '%s' while signing it", fileName)
+	}
+
+	if j.UseLegacyNotaryClient {
+		return j.signFileWithLegacyClient(fileName, archiveExtension, keyName, token, overwrite)
+	}
+
+	client := notary.NewClient(j.Conf.Services.NotaryURL, token)
+	err := client.SignFile(context.Background(), fileName, notary.Options{
+		KeyName:           keyName,
+		Comment:           "curator package signing",
+		ArchiveFileExt:    archiveExtension,
+		PackageFileSuffix: packageFileSuffix,
+		Outputs:           []string{"sig"},
+	})
+	if err != nil {
+		grip.Warningf("error signing file '%s': %s", fileName, err.Error())
+		return errors.Wrap(err, "problem with notary service client signing file")
+	}
+
+	grip.Noticef("successfully signed file: %s", fileName)
+
+	return nil
+}
+
+// signFileWithLegacyClient wraps the python notary-client.py script,
+// and is retained as a fallback for the native notary client above.
+func (j *Job) signFileWithLegacyClient(fileName, archiveExtension, keyName, token string, overwrite bool) error {
+	args := []string{
+		"notary-client.py",
+		"--key-name", keyName,
+		"--auth-token", token,
+		"--comment", "\"curator package signing\"",
+		"--notary-url", j.Conf.Services.NotaryURL,
+		"--archive-file-ext", archiveExtension,
+		"--outputs", "sig",
+	}
+
+	if overwrite {
+		args = append(args, "--package-file-suffix", "")
 	}
 
 	args = append(args, filepath.Base(fileName))
@@ -251,33 +306,32 @@ func (j *Job) signFile(fileName, archiveExtension string, overwrite bool) error
 
 // Run is the main execution entry point into repository building, and is a component
 func (j *Job) Run(ctx context.Context) {
-	bucket := sthree.GetBucketWithProfile(j.Distro.Bucket, j.Profile)
-	err := bucket.Open(ctx)
+	backend, err := openBackend(ctx, j.Distro, j.Profile)
 	if err != nil {
-		j.AddError(errors.Wrapf(err, "opening bucket %s", bucket))
+		j.AddError(errors.Wrapf(err, "opening backend for %s", j.Distro.Bucket))
 		return
 	}
-	defer bucket.Close()
+	defer backend.Close()
 
 	if j.DryRun {
 		// the error (second argument) will be caught (when we
 		// run open below)
-		bucket, err = bucket.DryRunClone(ctx)
+		backend, err = backend.DryRunClone(ctx)
 		if err != nil {
 			j.AddError(errors.Wrapf(err,
-				"problem getting bucket '%s' in dry-mode", bucket))
+				"problem getting backend '%s' in dry-mode", backend))
 			return
 		}
 
-		err := bucket.Open(ctx)
+		err := backend.Open(ctx)
 		if err != nil {
-			j.AddError(errors.Wrapf(err, "opening bucket %s [dry-run]", bucket))
+			j.AddError(errors.Wrapf(err, "opening backend %s [dry-run]", backend))
 			return
 		}
-		defer bucket.Close()
+		defer backend.Close()
 	}
 
-	bucket.NewFilePermission = s3.PublicRead
+	backend.SetNewFilePermission(sthree.PermissionPublicRead)
 
 	defer j.MarkComplete()
 	wg := &sync.WaitGroup{}
@@ -288,20 +342,27 @@ func (j *Job) Run(ctx context.Context) {
 	}
 	// at the moment there is only multiple repos for RPM distros
 	for _, remote := range j.Distro.Repos {
-		clonedBucket, err := bucket.Clone(ctx)
+		clonedBackend, err := backend.Clone(ctx)
 		if err != nil {
-			j.AddError(errors.Wrapf(err, "problem cloning bucket %s", bucket))
+			j.AddError(errors.Wrapf(err, "problem cloning backend %s", backend))
 			continue
 		}
 
 		j.workingDirs = append(j.workingDirs, remote)
 
 		wg.Add(1)
-		go func(remote string, b *sthree.Bucket) {
+		go func(remote string, b Backend) {
 			defer b.Close()
-			grip.Infof("rebuilding %s.%s", b, remote)
 			defer wg.Done()
 
+			if err := j.resourceController().acquireRepoSlot(ctx); err != nil {
+				j.AddError(errors.Wrapf(err, "acquiring repo slot for %s", remote))
+				return
+			}
+			defer j.resourceController().releaseRepoSlot()
+
+			grip.Infof("rebuilding %s.%s", b, remote)
+
 			local := filepath.Join(j.WorkSpace, remote)
 
 			var err error
@@ -333,6 +394,15 @@ func (j *Job) Run(ctx context.Context) {
 				return
 			}
 
+			if j.Distro.Type == OCI {
+				// the OCI builder pushes blobs and
+				// manifests straight to the registry as
+				// part of rebuildRepo, so there's no
+				// rendered repo layout left to sync to a
+				// Backend.
+				return
+			}
+
 			var syncSource string
 			var changedComponent string
 
@@ -355,7 +425,7 @@ func (j *Job) Run(ctx context.Context) {
 					syncSource, b, changedComponent))
 				return
 			}
-		}(remote, clonedBucket)
+		}(remote, clonedBackend)
 	}
 	wg.Wait()
 
@@ -0,0 +1,188 @@
+package repobuilder
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mongodb/grip"
+)
+
+// ResourceLimits bounds how aggressively a Job.Run (and the
+// linkPackages step it depends on) may consume resources shared with
+// other work on the same host. A zero value for any field leaves that
+// dimension unbounded, preserving today's fully-parallel behavior.
+type ResourceLimits struct {
+	// MaxParallelRepos bounds how many of a Distro's Repos are
+	// synced and rebuilt concurrently in Job.Run.
+	MaxParallelRepos int
+
+	// MaxParallelSigningOps bounds how many packages linkPackages
+	// signs concurrently.
+	MaxParallelSigningOps int
+
+	// MaxDiskBytes and MaxNetworkBytesPerSec are advisory caps
+	// surfaced to callers (e.g. an embedding amboy pool) that
+	// measure actual usage; Job itself does not meter disk or
+	// network I/O directly.
+	MaxDiskBytes          int64
+	MaxNetworkBytesPerSec int64
+}
+
+// ResourceUsage captures what an embedding scheduler observed while a
+// Job ran, so that ReportUsage can auto-tune the limits for the next
+// Job sharing the same RepositoryConfig.
+type ResourceUsage struct {
+	CPUPercent float64
+	RSSBytes   int64
+	DiskBytes  int64
+}
+
+// resourceController enforces a ResourceLimits via semaphores shared
+// across the repos and signing operations of every Job built against
+// the same RepositoryConfig (see controllerForConfig).
+type resourceController struct {
+	limits        ResourceLimits
+	repoTokens    chan struct{}
+	signingTokens chan struct{}
+}
+
+func newResourceController(limits ResourceLimits) *resourceController {
+	c := &resourceController{limits: limits}
+
+	if limits.MaxParallelRepos > 0 {
+		c.repoTokens = make(chan struct{}, limits.MaxParallelRepos)
+	}
+
+	if limits.MaxParallelSigningOps > 0 {
+		c.signingTokens = make(chan struct{}, limits.MaxParallelSigningOps)
+	}
+
+	return c
+}
+
+// acquireRepoSlot blocks until a repo-parallelism token is available,
+// or ctx is canceled. When no MaxParallelRepos limit is configured it
+// returns immediately.
+func (c *resourceController) acquireRepoSlot(ctx context.Context) error {
+	if c.repoTokens == nil {
+		return nil
+	}
+
+	select {
+	case c.repoTokens <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *resourceController) releaseRepoSlot() {
+	if c.repoTokens == nil {
+		return
+	}
+
+	<-c.repoTokens
+}
+
+// acquireSigningSlot and releaseSigningSlot work identically to the
+// repo-slot pair above, but gate linkPackages' concurrent signFile
+// calls instead.
+func (c *resourceController) acquireSigningSlot(ctx context.Context) error {
+	if c.signingTokens == nil {
+		return nil
+	}
+
+	select {
+	case c.signingTokens <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *resourceController) releaseSigningSlot() {
+	if c.signingTokens == nil {
+		return
+	}
+
+	<-c.signingTokens
+}
+
+// resourceControllers caches one resourceController per
+// RepositoryConfig, keyed by pointer, so that ReportResourceUsage's
+// auto-tuning is visible to every subsequent Job built against that
+// config, e.g. from a BackfillPlanner run.
+var (
+	resourceControllersMutex sync.Mutex
+	resourceControllers      = make(map[*RepositoryConfig]*resourceController)
+)
+
+func controllerForConfig(conf *RepositoryConfig) *resourceController {
+	resourceControllersMutex.Lock()
+	defer resourceControllersMutex.Unlock()
+
+	c, ok := resourceControllers[conf]
+	if !ok {
+		c = newResourceController(conf.ResourceLimits)
+		resourceControllers[conf] = c
+	}
+
+	return c
+}
+
+// ReportResourceUsage lets an embedding scheduler (e.g. an amboy pool
+// that tracks CPU/RSS per job) tell curator what a Job actually used.
+// When usage exceeds the configured budget, the limits for subsequent
+// Jobs built against conf are tuned downward by half, down to a floor
+// of one. This is deliberately conservative: it never raises limits
+// back up, since a scheduler is expected to call this only when it
+// observed contention it wants reduced.
+func ReportResourceUsage(conf *RepositoryConfig, usage ResourceUsage) {
+	resourceControllersMutex.Lock()
+	c, ok := resourceControllers[conf]
+	resourceControllersMutex.Unlock()
+
+	if !ok {
+		return
+	}
+
+	overBudget := (conf.ResourceLimits.MaxDiskBytes > 0 && usage.DiskBytes > conf.ResourceLimits.MaxDiskBytes) ||
+		usage.CPUPercent > 90
+	if !overBudget {
+		return
+	}
+
+	newLimits := c.limits
+	newLimits.MaxParallelRepos = tuneDown(c.limits.MaxParallelRepos)
+	newLimits.MaxParallelSigningOps = tuneDown(c.limits.MaxParallelSigningOps)
+	conf.ResourceLimits.MaxParallelRepos = newLimits.MaxParallelRepos
+	conf.ResourceLimits.MaxParallelSigningOps = newLimits.MaxParallelSigningOps
+
+	// Jobs already running hold a reference to c and keep using its
+	// token channels at their original size; swapping the map entry
+	// only changes what controllerForConfig hands out to Jobs started
+	// after this point, which is what "subsequent Jobs" means above.
+	next := newResourceController(newLimits)
+
+	resourceControllersMutex.Lock()
+	resourceControllers[conf] = next
+	resourceControllersMutex.Unlock()
+
+	grip.Warningf("reducing repobuilder concurrency after observed usage: repos=%d signing=%d",
+		newLimits.MaxParallelRepos, newLimits.MaxParallelSigningOps)
+}
+
+// tuneDown halves n, flooring at 1. n <= 0 means "unbounded" (see
+// ResourceLimits' doc comment) and is left alone rather than being
+// clamped down to a real limit.
+func tuneDown(n int) int {
+	if n <= 0 {
+		return n
+	}
+
+	if n <= 1 {
+		return 1
+	}
+
+	return n / 2
+}
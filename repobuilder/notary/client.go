@@ -0,0 +1,246 @@
+// Package notary implements a minimal native Go client for the
+// notary-server REST protocol. It exists to replace the
+// notary-client.py script that curator has historically shelled out to
+// when signing packages.
+package notary
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+)
+
+// Options describes a single signing request against the notary
+// service. The fields mirror the flags accepted by notary-client.py.
+type Options struct {
+	// KeyName is the name of the signing key the notary service
+	// should use, e.g. "server-4.4".
+	KeyName string
+
+	// Comment is attached to the signing request for audit
+	// purposes.
+	Comment string
+
+	// ArchiveFileExt only affects non-package files, and controls
+	// the extension of the detached signature the service
+	// produces.
+	ArchiveFileExt string
+
+	// PackageFileSuffix, when empty, tells the service to
+	// overwrite the uploaded file with its signed contents rather
+	// than producing a separate artifact.
+	PackageFileSuffix string
+
+	// Outputs requests a set of output types from the service,
+	// e.g. "sig".
+	Outputs []string
+}
+
+// Client talks directly to a notary-server instance over its REST
+// API, authenticated with a bearer token (conventionally sourced from
+// the NOTARY_TOKEN environment variable).
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// NewClient constructs a Client that submits signing requests to the
+// notary service running at baseURL, authenticating with token.
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: 10 * time.Minute},
+	}
+}
+
+type createResponse struct {
+	ID string `json:"id"`
+}
+
+type statusResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
+
+const (
+	statusComplete = "complete"
+	statusFailed   = "failed"
+)
+
+// SignFile uploads fileName to the notary service per opts, polls the
+// service until the signing job reaches a terminal state, and
+// downloads the resulting artifact to fileName+opts.PackageFileSuffix
+// when that suffix is set, to a "fileName.<ArchiveFileExt>" sidecar
+// when only ArchiveFileExt is set, and over fileName itself when both
+// are empty.
+func (c *Client) SignFile(ctx context.Context, fileName string, opts Options) error {
+	if c.token == "" {
+		return errors.New("the notary service auth token is not set")
+	}
+
+	id, err := c.submit(ctx, fileName, opts)
+	if err != nil {
+		return errors.Wrap(err, "problem submitting file for signing")
+	}
+
+	if err := c.waitForCompletion(ctx, id); err != nil {
+		return errors.Wrapf(err, "problem waiting for signing job %s", id)
+	}
+
+	dest := fileName
+	if opts.PackageFileSuffix != "" {
+		dest = fileName + opts.PackageFileSuffix
+	} else if len(opts.ArchiveFileExt) > 0 {
+		dest = fileName + "." + opts.ArchiveFileExt
+	}
+
+	return errors.Wrapf(c.download(ctx, id, dest), "downloading signed artifact for job %s", id)
+}
+
+func (c *Client) submit(ctx context.Context, fileName string, opts Options) (string, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return "", errors.Wrapf(err, "opening file %s for signing", fileName)
+	}
+	defer f.Close()
+
+	body := &strings.Builder{}
+	writer := multipart.NewWriter(body)
+
+	part, err := writer.CreateFormFile("file", filepath.Base(fileName))
+	if err != nil {
+		return "", errors.Wrap(err, "building multipart upload")
+	}
+	if _, err = io.Copy(part, f); err != nil {
+		return "", errors.Wrap(err, "copying file contents into request")
+	}
+
+	for field, value := range map[string]string{
+		"key_name":            opts.KeyName,
+		"comment":             opts.Comment,
+		"archive_file_ext":    opts.ArchiveFileExt,
+		"package_file_suffix": opts.PackageFileSuffix,
+		"outputs":             strings.Join(opts.Outputs, ","),
+	} {
+		if err = writer.WriteField(field, value); err != nil {
+			return "", errors.Wrapf(err, "writing form field %s", field)
+		}
+	}
+
+	if err = writer.Close(); err != nil {
+		return "", errors.Wrap(err, "closing multipart writer")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/sign", strings.NewReader(body.String()))
+	if err != nil {
+		return "", errors.Wrap(err, "building signing request")
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "making signing request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		return "", errors.Errorf("notary service returned status %d", resp.StatusCode)
+	}
+
+	out := createResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", errors.Wrap(err, "decoding signing response")
+	}
+
+	return out.ID, nil
+}
+
+func (c *Client) waitForCompletion(ctx context.Context, id string) error {
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		status, err := c.getStatus(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		switch status.Status {
+		case statusComplete:
+			return nil
+		case statusFailed:
+			return errors.Errorf("notary signing job %s failed: %s", id, status.Error)
+		default:
+			grip.Debugf("notary job %s is '%s', waiting", id, status.Status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "context canceled while waiting for notary job")
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Client) getStatus(ctx context.Context, id string) (*statusResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/sign/"+id, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "building status request")
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "making status request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("notary service returned status %d for job %s", resp.StatusCode, id)
+	}
+
+	out := &statusResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return nil, errors.Wrap(err, "decoding status response")
+	}
+
+	return out, nil
+}
+
+func (c *Client) download(ctx context.Context, id, dest string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/sign/"+id+"/download", nil)
+	if err != nil {
+		return errors.Wrap(err, "building download request")
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "making download request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("notary service returned status %d downloading job %s", resp.StatusCode, id)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "reading signed artifact")
+	}
+
+	return errors.Wrapf(ioutil.WriteFile(dest, data, 0644), "writing signed artifact to %s", dest)
+}
@@ -0,0 +1,365 @@
+package repobuilder
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mongodb/grip"
+	"github.com/pkg/errors"
+)
+
+// OCI identifies repository definitions that should be pushed to an
+// OCI-compliant registry (Harbor, Artifactory, GHCR, ...) as artifacts
+// of arbitrary media type, rather than assembled into a classic
+// apt/yum repo layout.
+const OCI = "oci"
+
+const (
+	ociMediaTypeImageConfig   = "application/vnd.oci.image.config.v1+json"
+	ociMediaTypeImageLayer    = "application/vnd.oci.image.layer.v1.tar"
+	ociMediaTypeImageManifest = "application/vnd.oci.image.manifest.v1+json"
+	ociMediaTypeImageIndex    = "application/vnd.oci.image.index.v1+json"
+	ociMediaTypeCosignSig     = "application/vnd.dev.cosign.simplesigning.v1+json"
+
+	ociAnnotationTitle = "org.opencontainers.image.title"
+)
+
+// setupOCIJob wires an ociJobImpl into j, mirroring setupDEBJob and
+// setupRPMJob.
+func setupOCIJob(j *Job) {
+	j.builder = &ociJobImpl{job: j}
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociManifest struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	MediaType     string          `json:"mediaType"`
+	Config        ociDescriptor   `json:"config"`
+	Layers        []ociDescriptor `json:"layers"`
+}
+
+type ociIndex struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	MediaType     string          `json:"mediaType"`
+	Manifests     []ociDescriptor `json:"manifests"`
+}
+
+// ociJobImpl packs injected packages as OCI artifacts and pushes them
+// to the registry named by job.Distro.Bucket (reused, here, as an
+// "<registry>/<repository>" reference rather than an S3 bucket name)
+// instead of syncing a rendered repo layout up to an object store.
+//
+// A single ociJobImpl is shared across every remote repo Job.Run
+// processes for a multi-repo Distro, each in its own goroutine, so
+// mutex guards blobsDir and index against concurrent injectPackage and
+// rebuildRepo calls.
+type ociJobImpl struct {
+	mutex    sync.Mutex
+	job      *Job
+	blobsDir string
+	index    ociIndex
+}
+
+// injectPackage stages each of the Job's PackagePaths as a set of
+// content-addressed OCI blobs (an empty image config, and the package
+// file itself as the sole layer) under a local staging directory, and
+// records each resulting manifest descriptor in the pending index. It returns the staging
+// directory, which rebuildRepo treats as its "changed" argument.
+func (o *ociJobImpl) injectPackage(local, location string) (string, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.blobsDir = filepath.Join(local, "oci", location, "blobs", "sha256")
+	if err := os.MkdirAll(o.blobsDir, 0755); err != nil {
+		return "", errors.Wrapf(err, "creating OCI blob directory %s", o.blobsDir)
+	}
+
+	for _, pkg := range o.job.PackagePaths {
+		if err := o.addPackage(pkg); err != nil {
+			return "", errors.Wrapf(err, "packing %s as an OCI artifact", pkg)
+		}
+	}
+
+	return filepath.Dir(filepath.Dir(o.blobsDir)), nil
+}
+
+func (o *ociJobImpl) addPackage(pkg string) error {
+	contents, err := ioutil.ReadFile(pkg)
+	if err != nil {
+		return errors.Wrapf(err, "reading %s", pkg)
+	}
+
+	layer, err := o.writeBlob(contents, ociMediaTypeImageLayer)
+	if err != nil {
+		return errors.Wrap(err, "writing package layer blob")
+	}
+	layer.Annotations = map[string]string{ociAnnotationTitle: filepath.Base(pkg)}
+
+	config, err := o.writeBlob([]byte("{}"), ociMediaTypeImageConfig)
+	if err != nil {
+		return errors.Wrap(err, "writing empty image config blob")
+	}
+
+	manifest := ociManifest{
+		SchemaVersion: 2,
+		MediaType:     ociMediaTypeImageManifest,
+		Config:        config,
+		Layers:        []ociDescriptor{layer},
+	}
+
+	manifestBytes, err := json.Marshal(manifest)
+	if err != nil {
+		return errors.Wrap(err, "marshaling OCI manifest")
+	}
+
+	descriptor, err := o.writeBlob(manifestBytes, ociMediaTypeImageManifest)
+	if err != nil {
+		return errors.Wrap(err, "writing manifest blob")
+	}
+	descriptor.Annotations = layer.Annotations
+
+	o.index.SchemaVersion = 2
+	o.index.MediaType = ociMediaTypeImageIndex
+	o.index.Manifests = append(o.index.Manifests, descriptor)
+
+	if sigPath := pkg + ".sig"; fileExists(sigPath) {
+		if err = o.addCosignSignature(sigPath, descriptor.Digest); err != nil {
+			return errors.Wrapf(err, "attaching cosign signature for %s", pkg)
+		}
+	}
+
+	return nil
+}
+
+// addCosignSignature pushes the detached signature signFile produced
+// alongside pkg as its own small OCI artifact, annotated with the
+// digest of the package manifest it signs, following the convention
+// cosign uses to associate signatures with the artifact they cover.
+func (o *ociJobImpl) addCosignSignature(sigPath, subjectDigest string) error {
+	contents, err := ioutil.ReadFile(sigPath)
+	if err != nil {
+		return errors.Wrapf(err, "reading signature %s", sigPath)
+	}
+
+	layer, err := o.writeBlob(contents, ociMediaTypeCosignSig)
+	if err != nil {
+		return err
+	}
+	layer.Annotations = map[string]string{"dev.cosignproject.cosign/signature": subjectDigest}
+
+	manifest := ociManifest{
+		SchemaVersion: 2,
+		MediaType:     ociMediaTypeImageManifest,
+		Config:        ociDescriptor{MediaType: ociMediaTypeImageConfig, Digest: emptyConfigDigest, Size: 2},
+		Layers:        []ociDescriptor{layer},
+	}
+
+	manifestBytes, err := json.Marshal(manifest)
+	if err != nil {
+		return errors.Wrap(err, "marshaling signature manifest")
+	}
+
+	descriptor, err := o.writeBlob(manifestBytes, ociMediaTypeImageManifest)
+	if err != nil {
+		return err
+	}
+
+	o.index.Manifests = append(o.index.Manifests, descriptor)
+	return nil
+}
+
+const emptyConfigDigest = "sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"
+
+func (o *ociJobImpl) writeBlob(contents []byte, mediaType string) (ociDescriptor, error) {
+	sum := sha256.Sum256(contents)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+
+	path := filepath.Join(o.blobsDir, hex.EncodeToString(sum[:]))
+	if err := ioutil.WriteFile(path, contents, 0644); err != nil {
+		return ociDescriptor{}, errors.Wrapf(err, "writing blob %s", digest)
+	}
+
+	return ociDescriptor{MediaType: mediaType, Digest: digest, Size: int64(len(contents))}, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// rebuildRepo writes the accumulated OCI index to changed and pushes
+// every blob and the index itself to the configured registry,
+// reusing signFile's already-produced cosign signatures rather than
+// re-signing here. It only holds o.mutex long enough to snapshot the
+// index and blob directory recorded so far; the registry pushes
+// themselves run unlocked so a slow push on one remote doesn't block
+// injectPackage for the others.
+func (o *ociJobImpl) rebuildRepo(changed string) error {
+	o.mutex.Lock()
+	blobsDir := o.blobsDir
+	manifests := make([]ociDescriptor, len(o.index.Manifests))
+	copy(manifests, o.index.Manifests)
+	index := o.index
+	index.Manifests = manifests
+	o.mutex.Unlock()
+
+	indexBytes, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "marshaling OCI index")
+	}
+
+	if err = ioutil.WriteFile(filepath.Join(changed, "index.json"), indexBytes, 0644); err != nil {
+		return errors.Wrap(err, "writing OCI index")
+	}
+
+	pusher := newOCIRegistryPusher(o.job.Distro.Bucket)
+
+	catcher := grip.NewCatcher()
+	for _, manifest := range manifests {
+		catcher.Add(pusher.pushManifest(context.Background(), blobsDir, manifest))
+	}
+
+	return catcher.Resolve()
+}
+
+// ociRegistryPusher pushes blobs and manifests to an OCI Distribution
+// Spec registry using plain HTTP, in the same spirit as the oras-go
+// push path, rather than depending on a full registry client library.
+type ociRegistryPusher struct {
+	repository string
+	http       *http.Client
+	token      string
+}
+
+func newOCIRegistryPusher(repository string) *ociRegistryPusher {
+	return &ociRegistryPusher{
+		repository: repository,
+		http:       &http.Client{},
+		token:      os.Getenv("ORAS_REGISTRY_TOKEN"),
+	}
+}
+
+func (p *ociRegistryPusher) pushManifest(ctx context.Context, blobsDir string, manifest ociDescriptor) error {
+	manifestPath := filepath.Join(blobsDir, strings.TrimPrefix(manifest.Digest, "sha256:"))
+	manifestBytes, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		return errors.Wrapf(err, "reading manifest blob %s", manifest.Digest)
+	}
+
+	var parsed ociManifest
+	if err = json.Unmarshal(manifestBytes, &parsed); err != nil {
+		return errors.Wrapf(err, "parsing manifest blob %s", manifest.Digest)
+	}
+
+	for _, layer := range append([]ociDescriptor{parsed.Config}, parsed.Layers...) {
+		if err = p.pushBlob(ctx, blobsDir, layer); err != nil {
+			return errors.Wrapf(err, "pushing blob %s", layer.Digest)
+		}
+	}
+
+	url := fmt.Sprintf("%s/v2/%s/manifests/%s", p.registryBaseURL(), p.repositoryPath(), manifest.Digest)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(manifestBytes))
+	if err != nil {
+		return errors.Wrap(err, "building manifest push request")
+	}
+	req.Header.Set("Content-Type", parsed.MediaType)
+	p.authorize(req)
+
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "pushing manifest")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return errors.Errorf("registry rejected manifest %s with status %d", manifest.Digest, resp.StatusCode)
+	}
+
+	grip.Noticef("pushed OCI manifest %s to %s/%s", manifest.Digest, p.registryBaseURL(), p.repositoryPath())
+
+	return nil
+}
+
+func (p *ociRegistryPusher) pushBlob(ctx context.Context, blobsDir string, blob ociDescriptor) error {
+	path := filepath.Join(blobsDir, strings.TrimPrefix(blob.Digest, "sha256:"))
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "reading blob %s", blob.Digest)
+	}
+
+	initURL := fmt.Sprintf("%s/v2/%s/blobs/uploads/", p.registryBaseURL(), p.repositoryPath())
+	initReq, err := http.NewRequestWithContext(ctx, http.MethodPost, initURL, nil)
+	if err != nil {
+		return errors.Wrap(err, "building blob upload initiation request")
+	}
+	p.authorize(initReq)
+
+	initResp, err := p.http.Do(initReq)
+	if err != nil {
+		return errors.Wrap(err, "initiating blob upload")
+	}
+	defer initResp.Body.Close()
+
+	if initResp.StatusCode != http.StatusAccepted {
+		return errors.Errorf("registry rejected blob upload initiation with status %d", initResp.StatusCode)
+	}
+
+	uploadURL := initResp.Header.Get("Location") + "&digest=" + blob.Digest
+	putReq, err := http.NewRequestWithContext(ctx, http.MethodPut, uploadURL, bytes.NewReader(contents))
+	if err != nil {
+		return errors.Wrap(err, "building blob upload request")
+	}
+	putReq.Header.Set("Content-Type", "application/octet-stream")
+	p.authorize(putReq)
+
+	putResp, err := p.http.Do(putReq)
+	if err != nil {
+		return errors.Wrap(err, "uploading blob")
+	}
+	defer putResp.Body.Close()
+
+	if putResp.StatusCode != http.StatusCreated {
+		return errors.Errorf("registry rejected blob %s with status %d", blob.Digest, putResp.StatusCode)
+	}
+
+	return nil
+}
+
+func (p *ociRegistryPusher) authorize(req *http.Request) {
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+}
+
+// registryBaseURL and repositoryPath split job.Distro.Bucket, which
+// for OCI distros is configured as "<registry-host>/<repository>",
+// e.g. "ghcr.io/mongodb/mongodb-org".
+func (p *ociRegistryPusher) registryBaseURL() string {
+	host := strings.SplitN(p.repository, "/", 2)[0]
+	return "https://" + host
+}
+
+func (p *ociRegistryPusher) repositoryPath() string {
+	parts := strings.SplitN(p.repository, "/", 2)
+	if len(parts) < 2 {
+		return ""
+	}
+
+	return parts[1]
+}
@@ -1,14 +1,20 @@
 package sthree
 
 import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"io/ioutil"
+	"hash"
+	"io"
 	"math/rand"
 	"mime"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/goamz/goamz/aws"
@@ -17,9 +23,37 @@ import (
 	"github.com/mongodb/amboy"
 	"github.com/mongodb/amboy/queue"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/tychoish/grip"
 )
 
+// ObjectPermission is a backend-agnostic description of the
+// visibility granted to objects written by a Bucket, so that callers
+// who target more than one object-store implementation (see
+// repobuilder.Backend) don't need to depend on goamz's s3.ACL type
+// directly.
+type ObjectPermission string
+
+const (
+	PermissionPrivate    ObjectPermission = "private"
+	PermissionPublicRead ObjectPermission = "public-read"
+)
+
+// SyncOptions carries per-call settings for SyncFrom and SyncTo
+// operations.
+type SyncOptions struct {
+	// Timeout is an overall duration for the sync operation.
+	// SyncFrom and SyncTo do not apply it: the only deadline they
+	// observe is that of the context passed to them.
+	Timeout time.Duration
+}
+
+// NewDefaultSyncOptions returns the SyncOptions used when callers
+// don't need to customize sync behavior.
+func NewDefaultSyncOptions() SyncOptions {
+	return SyncOptions{}
+}
+
 func init() {
 	// adds, at process startup time.
 	grip.CatchError(mime.AddExtensionType(".deb", "application/octet-stream"))
@@ -44,35 +78,171 @@ func getBackoff() *backoff.Backoff {
 	}
 }
 
+// sleepOrCancel waits for d, returning early with ctx.Err() if ctx is
+// canceled first, so a retry loop's backoff never outlives its caller.
+func sleepOrCancel(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
 // AWSConnectionConfiguration defines configuration, including
 // authentication credentials and AWS region, used when creating new
 // connections to AWS components.
 type AWSConnectionConfiguration struct {
 	// AWS auth credentials, using a type defined in the goamz
-	// package.
+	// package. Unused when Driver is DriverAWSV2, which resolves
+	// credentials through its own chain (including IAM
+	// role/instance-profile credentials) unless overridden below.
 	Auth aws.Auth
 
 	// Specify a region to use in the AWS connection. For S3
 	// operations this should not matter.
 	Region aws.Region
+
+	// Driver selects which S3 client implementation the Bucket
+	// uses. The zero value behaves as DriverGoamz, preserving
+	// existing behavior.
+	Driver DriverType
+
+	// Endpoint overrides the S3 endpoint used by DriverAWSV2, for
+	// talking to S3-compatible services instead of AWS.
+	Endpoint string
+}
+
+// PutOptions customizes a single Put (or sync upload), beyond the
+// content type and ACL Bucket already derives automatically.
+type PutOptions struct {
+	// PartSize and Concurrency configure the underlying
+	// uploader/downloader for drivers that support true multipart
+	// streaming (currently DriverAWSV2). Drivers that don't,
+	// notably DriverGoamz, ignore them. A zero value leaves the
+	// driver's own default in place.
+	PartSize    int64
+	Concurrency int
+
+	// Metadata is stored as object metadata (the driver-agnostic
+	// equivalent of goamz's "x-amz-meta-*" headers). Put uses this
+	// to record the content digest computed under the Bucket's
+	// ChecksumAlgorithm.
+	Metadata map[string]string
+
+	// ServerSideEncryption, SSEKMSKeyID, SSECustomerKey, and
+	// StorageClass mirror the Bucket-level settings of the same
+	// name (see SetServerSideEncryption, SetSSECustomerKey, and
+	// SetStorageClass) onto a single Put/sync upload, including
+	// each part of a multipart upload.
+	ServerSideEncryption ServerSideEncryption
+	SSEKMSKeyID          string
+	SSECustomerKey       *SSECustomerKey
+	StorageClass         StorageClass
 }
 
+// ChecksumAlgorithm selects how Put computes and stores a digest of
+// the content it uploads, so that a later sync can trust the stored
+// digest instead of re-fetching the object to compare bytes.
+type ChecksumAlgorithm string
+
+const (
+	ChecksumSHA256 ChecksumAlgorithm = "sha256"
+	ChecksumMD5    ChecksumAlgorithm = "md5"
+	ChecksumNone   ChecksumAlgorithm = "none"
+)
+
+// checksumMetadataKey is the object metadata key Put stores alg's
+// digest under, and the key remoteChecksum looks for on HEAD.
+func checksumMetadataKey(alg ChecksumAlgorithm) string {
+	switch alg {
+	case ChecksumMD5:
+		return "Content-Md5"
+	default:
+		return "Content-Sha256"
+	}
+}
+
+// GetOptions customizes a single Get (or sync download); see
+// PutOptions for the meaning of PartSize and Concurrency.
+type GetOptions struct {
+	PartSize    int64
+	Concurrency int
+
+	// SSECustomerKey must be set to the same key Put used when the
+	// object was uploaded with SSE-C: S3 rejects a GET for an SSE-C
+	// object that doesn't present the matching key.
+	SSECustomerKey *SSECustomerKey
+}
+
+const (
+	defaultPartSize          int64 = 5 * 1024 * 1024 // 5 MiB
+	defaultUploadConcurrency       = 5
+	defaultDownloadConcurrency     = 13
+)
+
 // Bucket defines a tracking object for a bucket. Create access using the
 // global GetBucket factory, which allows users to pool bucket operations.
 type Bucket struct {
 	// The permission defined by NewFilePermission is used for all
 	// Put operations in the bucket.
-	NewFilePermission s3.ACL
-	open              bool
-	dryRun            bool
-	credentials       AWSConnectionConfiguration
-	bucket            *s3.Bucket
-	s3                *s3.S3
-	registry          *bucketRegistry
-	name              string
-	numJobs           int
-	numRetries        int
-	queue             amboy.Queue
+	NewFilePermission   s3.ACL
+	open                bool
+	dryRun              bool
+	credentials         AWSConnectionConfiguration
+	driver              Driver
+	permission          ObjectPermission
+	registry            *bucketRegistry
+	name                string
+	numJobs             int
+	numRetries          int
+	partSize            int64
+	uploadConcurrency   int
+	downloadConcurrency int
+	checksumAlgorithm   ChecksumAlgorithm
+	checksums           *remoteChecksumCache
+	metrics             *bucketMetrics
+	sse                 ServerSideEncryption
+	sseKMSKeyID         string
+	sseCustomerKey      *SSECustomerKey
+	storageClass        StorageClass
+	trashLifetime       time.Duration
+	unsafeDelete        bool
+	queue               amboy.Queue
+}
+
+// WithMetrics registers Prometheus collectors, scoped to reg, that
+// record per-operation counters, latency histograms, in-flight
+// gauges, retry counts, and bytes-in/bytes-out for this Bucket's Put,
+// Get, Exists, Delete, DelMulti, List, and sync operations. labels are
+// added to bucket name, region, and driver as constant labels on
+// every collector, and the Bucket is returned so calls can chain off
+// of GetBucket/NewBucket. Safe to leave uncalled: every instrumented
+// method tolerates a nil *bucketMetrics.
+func (b *Bucket) WithMetrics(reg *prometheus.Registry, labels map[string]string) *Bucket {
+	constLabels := prometheus.Labels{
+		"bucket": b.name,
+		"region": b.credentials.Region.Name,
+		"driver": string(b.credentials.Driver),
+	}
+	for k, v := range labels {
+		constLabels[k] = v
+	}
+
+	b.metrics = newBucketMetrics(reg, constLabels)
+	return b
+}
+
+// remoteChecksumCache memoizes remoteChecksum's HEAD lookups for the
+// duration of a single sync run, so that SyncTo/SyncFrom issue at
+// most one HEAD per object no matter how many times a job consults
+// it.
+type remoteChecksumCache struct {
+	mu   sync.Mutex
+	data map[string]string
 }
 
 // NewBucket clones the settings of one bucket into a new bucket. The
@@ -80,11 +250,23 @@ type Bucket struct {
 // it is closed.
 func (b *Bucket) NewBucket(name string) *Bucket {
 	new := &Bucket{
-		name:              name,
-		NewFilePermission: b.NewFilePermission,
-		credentials:       b.credentials,
-		numJobs:           b.numJobs,
-		numRetries:        20,
+		name:                name,
+		NewFilePermission:   b.NewFilePermission,
+		permission:          b.permission,
+		metrics:             b.metrics,
+		credentials:         b.credentials,
+		numJobs:             b.numJobs,
+		numRetries:          20,
+		partSize:            b.partSize,
+		uploadConcurrency:   b.uploadConcurrency,
+		downloadConcurrency: b.downloadConcurrency,
+		checksumAlgorithm:   b.checksumAlgorithm,
+		sse:                 b.sse,
+		sseKMSKeyID:         b.sseKMSKeyID,
+		sseCustomerKey:      b.sseCustomerKey,
+		storageClass:        b.storageClass,
+		trashLifetime:       b.trashLifetime,
+		unsafeDelete:        b.unsafeDelete,
 	}
 
 	b.registry.registerBucket(new)
@@ -95,8 +277,8 @@ func (b *Bucket) NewBucket(name string) *Bucket {
 // resource, that runs with dry-run mode. In this mode, all PUT
 // and DELETE operations are no-ops, with more logging, but all other
 // operations (including "GET" operations) are as normal.
-func (b *Bucket) DryRunClone() (*Bucket, error) {
-	clone, err := b.Clone()
+func (b *Bucket) DryRunClone(ctx context.Context) (*Bucket, error) {
+	clone, err := b.Clone(ctx)
 
 	if err != nil {
 		return nil, err
@@ -109,18 +291,30 @@ func (b *Bucket) DryRunClone() (*Bucket, error) {
 // Clone returns a copy of the existing bucket, which is not a shared
 // resource. Useful when you want to run bucket operations with sync
 // from/to operations, issued from different threads.
-func (b *Bucket) Clone() (*Bucket, error) {
+func (b *Bucket) Clone(ctx context.Context) (*Bucket, error) {
 	clone := &Bucket{
-		name:              b.name,
-		open:              false,
-		NewFilePermission: b.NewFilePermission,
-		credentials:       b.credentials,
-		numJobs:           b.numJobs,
-		numRetries:        b.numRetries,
+		name:                b.name,
+		open:                false,
+		NewFilePermission:   b.NewFilePermission,
+		permission:          b.permission,
+		metrics:             b.metrics,
+		credentials:         b.credentials,
+		numJobs:             b.numJobs,
+		numRetries:          b.numRetries,
+		partSize:            b.partSize,
+		uploadConcurrency:   b.uploadConcurrency,
+		downloadConcurrency: b.downloadConcurrency,
+		checksumAlgorithm:   b.checksumAlgorithm,
+		sse:                 b.sse,
+		sseKMSKeyID:         b.sseKMSKeyID,
+		sseCustomerKey:      b.sseCustomerKey,
+		storageClass:        b.storageClass,
+		trashLifetime:       b.trashLifetime,
+		unsafeDelete:        b.unsafeDelete,
 	}
 
 	if b.open {
-		err := clone.Open()
+		err := clone.Open(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -133,6 +327,14 @@ func (b *Bucket) String() string {
 	return b.name
 }
 
+// SetNewFilePermission sets the ACL applied to objects written by Put
+// and SyncTo, translating the backend-agnostic ObjectPermission into
+// the s3.ACL value goamz expects.
+func (b *Bucket) SetNewFilePermission(perm ObjectPermission) {
+	b.permission = perm
+	b.NewFilePermission = goamzACL(perm)
+}
+
 // SetCredentials allows you to override the configured credentials in
 // the Bucket instance. Bucket instances have default credentials
 // picked from either the AWS_ACCESS_KEY_ID and AWS_ACCESS_KEY
@@ -146,8 +348,7 @@ func (b *Bucket) String() string {
 // jobs in undefined ways.
 func (b *Bucket) SetCredentials(c AWSConnectionConfiguration) {
 	b.credentials = c
-	b.s3 = s3.New(b.credentials.Auth, b.credentials.Region)
-	b.bucket = b.s3.Bucket(b.name)
+	b.driver = newDriver(b.credentials, b.name)
 }
 
 // SetNumJobs allows callers to change the number of worker threads
@@ -173,28 +374,261 @@ func (b *Bucket) SetNumRetries(n int) error {
 	return nil
 }
 
+// SetPartSize allows callers to change the chunk size used to stream
+// multipart uploads and downloads. A zero or negative value restores
+// the default of 5 MiB.
+func (b *Bucket) SetPartSize(size int64) {
+	b.partSize = size
+}
+
+// SetUploadConcurrency allows callers to change how many parts of a
+// Put (or sync upload) are streamed to S3 concurrently. A zero or
+// negative value restores the default of 5.
+func (b *Bucket) SetUploadConcurrency(n int) {
+	b.uploadConcurrency = n
+}
+
+// SetDownloadConcurrency allows callers to change how many parts of a
+// Get (or sync download) are streamed from S3 concurrently. A zero or
+// negative value restores the default of 13.
+func (b *Bucket) SetDownloadConcurrency(n int) {
+	b.downloadConcurrency = n
+}
+
+func (b *Bucket) putOptions() PutOptions {
+	return PutOptions{
+		PartSize:             b.effectivePartSize(),
+		Concurrency:          b.effectiveUploadConcurrency(),
+		ServerSideEncryption: b.sse,
+		SSEKMSKeyID:          b.sseKMSKeyID,
+		SSECustomerKey:       b.sseCustomerKey,
+		StorageClass:         b.storageClass,
+	}
+}
+
+func (b *Bucket) getOptions() GetOptions {
+	return GetOptions{
+		PartSize:       b.effectivePartSize(),
+		Concurrency:    b.effectiveDownloadConcurrency(),
+		SSECustomerKey: b.sseCustomerKey,
+	}
+}
+
+func (b *Bucket) effectivePartSize() int64 {
+	if b.partSize <= 0 {
+		return defaultPartSize
+	}
+
+	return b.partSize
+}
+
+func (b *Bucket) effectiveUploadConcurrency() int {
+	if b.uploadConcurrency <= 0 {
+		return defaultUploadConcurrency
+	}
+
+	return b.uploadConcurrency
+}
+
+func (b *Bucket) effectiveDownloadConcurrency() int {
+	if b.downloadConcurrency <= 0 {
+		return defaultDownloadConcurrency
+	}
+
+	return b.downloadConcurrency
+}
+
+// SetChecksumAlgorithm controls whether Put computes a content digest
+// and stores it as object metadata, and which algorithm it uses. The
+// zero value behaves as ChecksumSHA256; pass ChecksumNone to disable
+// this entirely (e.g. for buckets where objects are written by other
+// tools that won't maintain the metadata).
+func (b *Bucket) SetChecksumAlgorithm(alg ChecksumAlgorithm) {
+	b.checksumAlgorithm = alg
+}
+
+// SetServerSideEncryption configures Put (and sync uploads) to
+// request S3-managed (AES256) or KMS-managed (aws:kms) server-side
+// encryption on every object they write, including each part of a
+// multipart upload. kmsKeyID is required, and only used, when sse is
+// SSEKMS. Misconfiguration (an unrecognized sse, or a missing
+// kmsKeyID) isn't rejected here; it's caught by Open so it fails
+// before any upload is attempted, not on the first one.
+func (b *Bucket) SetServerSideEncryption(sse ServerSideEncryption, kmsKeyID string) {
+	b.sse = sse
+	b.sseKMSKeyID = kmsKeyID
+}
+
+// SetSSECustomerKey configures Put and Get to use SSE-C (a customer-
+// supplied encryption key) instead of S3- or KMS-managed encryption.
+// It's mutually exclusive with SetServerSideEncryption; Open reports
+// an error if both are set.
+func (b *Bucket) SetSSECustomerKey(key SSECustomerKey) {
+	b.sseCustomerKey = &key
+}
+
+// SetStorageClass controls the S3 storage tier Put (and sync uploads)
+// write objects to, for curator artifacts that want a cold-storage
+// tier instead of the bucket's default.
+func (b *Bucket) SetStorageClass(class StorageClass) {
+	b.storageClass = class
+}
+
+// SetTrashLifetime models Delete, DeleteMany, DeletePrefix, and
+// DeleteMatching on Arvados keepstore's trash semantics: instead of
+// deleting an object outright, deleteGroup copies it under the
+// trash/ prefix and only then removes the original, so an operator
+// can restore it with Untrash until EmptyTrash permanently removes
+// the trash copy. Any d > 0 enables trashing and zero (the default)
+// disables it; d is not otherwise used: no expiration is set on the
+// trash copies, and how old they must be before removal is the
+// olderThan passed to EmptyTrash. See SetUnsafeDelete to opt back
+// into immediate deletion even with a lifetime configured.
+func (b *Bucket) SetTrashLifetime(d time.Duration) {
+	b.trashLifetime = d
+}
+
+// SetUnsafeDelete, when true, makes Delete and friends bypass
+// SetTrashLifetime and remove objects immediately, matching curator's
+// historical behavior. It has no effect when TrashLifetime is zero,
+// since deletes are already immediate in that case.
+func (b *Bucket) SetUnsafeDelete(unsafe bool) {
+	b.unsafeDelete = unsafe
+}
+
+func (b *Bucket) effectiveChecksumAlgorithm() ChecksumAlgorithm {
+	if b.checksumAlgorithm == "" {
+		return ChecksumSHA256
+	}
+
+	return b.checksumAlgorithm
+}
+
+// checksumCache returns the Bucket's remote-digest cache, creating it
+// on first use.
+func (b *Bucket) checksumCache() *remoteChecksumCache {
+	if b.checksums == nil {
+		b.checksums = &remoteChecksumCache{data: make(map[string]string)}
+	}
+
+	return b.checksums
+}
+
+// remoteChecksum returns the digest path was stored with by Put, HEAD-
+// ing the object at most once per Bucket regardless of how many times
+// it's consulted during a sync run. ok is false when the object has
+// no stored digest, e.g. it predates this field or was uploaded with
+// ChecksumNone.
+func (b *Bucket) remoteChecksum(ctx context.Context, path string) (digest string, ok bool, err error) {
+	cache := b.checksumCache()
+	key := checksumMetadataKey(b.effectiveChecksumAlgorithm())
+
+	cache.mu.Lock()
+	if cached, found := cache.data[path]; found {
+		cache.mu.Unlock()
+		return cached, cached != "", nil
+	}
+	cache.mu.Unlock()
+
+	meta, err := b.driver.Head(ctx, path)
+	if err != nil {
+		return "", false, errors.Wrapf(err, "error s3.HEAD for %s/%s", b.name, path)
+	}
+
+	digest = meta[key]
+
+	cache.mu.Lock()
+	cache.data[path] = digest
+	cache.mu.Unlock()
+
+	return digest, digest != "", nil
+}
+
+// unchanged reports whether fileName's local content already matches
+// path's remote stored digest, so syncTo/syncFrom can skip a transfer
+// instead of always re-uploading or re-downloading. It reports changed
+// (false) whenever there's nothing trustworthy to compare: a
+// ChecksumNone algorithm, a fileName that doesn't exist locally yet,
+// or a remote object with no stored digest (e.g. it predates this
+// field).
+func (b *Bucket) unchanged(ctx context.Context, fileName, path string) (bool, error) {
+	alg := b.effectiveChecksumAlgorithm()
+	if alg == ChecksumNone {
+		return false, nil
+	}
+
+	if _, err := os.Stat(fileName); err != nil {
+		return false, nil
+	}
+
+	remoteDigest, ok, err := b.remoteChecksum(ctx, path)
+	if err != nil {
+		return false, errors.Wrapf(err, "checking remote checksum for %s/%s", b.name, path)
+	}
+	if !ok {
+		return false, nil
+	}
+
+	_, localDigest, err := localChecksum(fileName, alg)
+	if err != nil {
+		return false, errors.Wrapf(err, "checking local checksum for %s", fileName)
+	}
+
+	return localDigest == remoteDigest, nil
+}
+
+// localChecksum computes the digest of fileName under alg, returning
+// the metadata key it belongs under and the hex-encoded digest. A
+// ChecksumNone algorithm returns an empty key and digest.
+func localChecksum(fileName string, alg ChecksumAlgorithm) (key, digest string, err error) {
+	if alg == ChecksumNone {
+		return "", "", nil
+	}
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "opening file '%s' to compute checksum", fileName)
+	}
+	defer f.Close()
+
+	var h hash.Hash
+	if alg == ChecksumMD5 {
+		h = md5.New()
+	} else {
+		h = sha256.New()
+	}
+
+	if _, err = io.Copy(h, f); err != nil {
+		return "", "", errors.Wrapf(err, "hashing file '%s'", fileName)
+	}
+
+	return checksumMetadataKey(alg), hex.EncodeToString(h.Sum(nil)), nil
+}
+
 // Open creates connections to S3 and starts a the worker pool to
 // process sync to/from jobs. Returns an error if there are issues
 // creating creating the worker queue. Does *not* return an error if
 // the Bucket has been opened, and is a noop in this case.
-func (b *Bucket) Open() error {
+func (b *Bucket) Open(ctx context.Context) error {
 	if b.open {
 		return nil
 	}
 
-	if b.s3 == nil {
-		b.s3 = s3.New(b.credentials.Auth, b.credentials.Region)
+	if err := validateEncryptionConfig(b.sse, b.sseKMSKeyID, b.sseCustomerKey, b.storageClass); err != nil {
+		return errors.Wrap(err, "validating encryption and storage class configuration")
 	}
 
-	if b.bucket == nil || b.bucket.Name != b.name {
-		b.bucket = b.s3.Bucket(b.name)
+	if b.driver == nil {
+		b.driver = newDriver(b.credentials, b.name)
 	}
 
 	b.open = true
 
 	b.queue = queue.NewLocalUnordered(b.numJobs)
 
-	return errors.Wrap(b.queue.Start(), "starting worker queue for sync jobs")
+	// ctx governs the worker pool's lifetime: canceling it stops the
+	// queue's workers, independent of the later Close() call.
+	return errors.Wrap(b.queue.Start(ctx), "starting worker queue for sync jobs")
 }
 
 // Close waits for all pending jobs to return and then releases all
@@ -209,11 +643,16 @@ func (b *Bucket) Close() {
 	}
 }
 
-// list returns a channel of strings of key names in the bucket. Allows
-// you to specify a prefix key that will limit the results returned in
-// the channel. If you do not want to limit using a prefix, pass an
-// empty string as the sole argument for list().
-func (b *Bucket) list(prefix string) <-chan s3.Key {
+// list returns a channel of s3.Key values for the objects in the
+// bucket. Allows you to specify a prefix key that will limit the
+// results returned in the channel. If you do not want to limit using a
+// prefix, pass an empty string as the sole argument for list().
+//
+// list is built on top of Driver.List, so it (and everything built on
+// top of it -- contents, SyncTo/SyncFrom, DeleteMany/DeletePrefix/
+// DeleteMatching, EmptyTrash) works the same way against DriverAWSV2
+// as it does against the default goamz driver.
+func (b *Bucket) list(ctx context.Context, prefix string) <-chan s3.Key {
 	output := make(chan s3.Key, 100)
 
 	// if the prefix doesn't have a trailing slash and isn't the
@@ -222,26 +661,30 @@ func (b *Bucket) list(prefix string) <-chan s3.Key {
 	if prefix != "" && !strings.HasSuffix(prefix, "/") {
 		prefix += "/"
 	}
+
 	go func() {
-		var lastKey string
-		for {
-			results, err := b.bucket.List(prefix, "", lastKey, 1000)
-			if err != nil {
-				grip.Error(err)
-				break
-			}
+		done := b.metrics.start("list")
+		defer close(output)
 
-			for _, key := range results.Contents {
-				lastKey = key.Key
+		items, err := b.driver.List(ctx, prefix)
+		if err != nil {
+			grip.Error(err)
+			done(err)
+			return
+		}
 
-				output <- key
-			}
+		for item := range items {
+			key := s3.Key{Key: item.Key, LastModified: item.LastModified, Size: item.Size, ETag: item.ETag}
 
-			if !results.IsTruncated {
-				break
+			select {
+			case output <- key:
+			case <-ctx.Done():
+				done(ctx.Err())
+				return
 			}
 		}
-		close(output)
+
+		done(ctx.Err())
 	}()
 
 	return output
@@ -249,10 +692,10 @@ func (b *Bucket) list(prefix string) <-chan s3.Key {
 
 // contents wraps and operates as list, but returns a map of names to
 // s3Item objects for random access patterns.
-func (b *Bucket) contents(prefix string) map[string]s3.Key {
+func (b *Bucket) contents(ctx context.Context, prefix string) map[string]s3.Key {
 	output := make(map[string]s3.Key)
 
-	for file := range b.list(prefix) {
+	for file := range b.list(ctx, prefix) {
 		output[file.Key] = file
 	}
 
@@ -260,26 +703,34 @@ func (b *Bucket) contents(prefix string) map[string]s3.Key {
 }
 
 // Exists checks to see if a key exists in the bucket, retrying the request, if needed.
-func (b *Bucket) Exists(path string) (bool, error) {
+func (b *Bucket) Exists(ctx context.Context, path string) (bool, error) {
 	var exists bool
-	var err error
 
-	backoff := getBackoff()
+	err := b.metrics.observe("exists", func() error {
+		var err error
 
-	for i := 1; i <= b.numRetries; i++ {
-		exists, err = b.bucket.Exists(path)
-		if err == nil {
-			return exists, nil
-		}
+		backoff := getBackoff()
+
+		for i := 1; i <= b.numRetries; i++ {
+			exists, err = b.driver.Exists(ctx, path)
+			if err == nil {
+				return nil
+			}
 
-		err = errors.Wrapf(err, "error s3.EXISTS for %s/%s on attempt %d", path, b.name, i)
+			err = errors.Wrapf(err, "error s3.EXISTS for %s/%s on attempt %d", path, b.name, i)
 
-		if i < b.numRetries {
-			grip.Warningln(err, "retrying...")
-			time.Sleep(backoff.Duration())
-			grip.Debugf("retrying s3.EXISTS %d of %d, for %s", i, b.numRetries, path)
+			if i < b.numRetries {
+				b.metrics.recordRetry("exists")
+				grip.Warningln(err, "retrying...")
+				if sleepErr := sleepOrCancel(ctx, backoff.Duration()); sleepErr != nil {
+					return errors.Wrap(sleepErr, "canceled while retrying s3.EXISTS")
+				}
+				grip.Debugf("retrying s3.EXISTS %d of %d, for %s", i, b.numRetries, path)
+			}
 		}
-	}
+
+		return err
+	})
 
 	return exists, err
 }
@@ -290,48 +741,75 @@ func (b *Bucket) Exists(path string) (bool, error) {
 // extension is not known. The permissions on the object use the value
 // of the Bucket.NewFilePermission property. Returns an error if the
 // underlying Put operation returns an error.
-func (b *Bucket) Put(fileName, path string) error {
-	if _, err := os.Stat(fileName); os.IsNotExist(err) {
+func (b *Bucket) Put(ctx context.Context, fileName, path string) error {
+	stat, err := os.Stat(fileName)
+	if os.IsNotExist(err) {
 		return errors.Errorf("file '%s' does not exist", fileName)
 	}
 
 	mimeType := getMimeType(fileName)
-	contents, err := ioutil.ReadFile(fileName)
-
-	if err != nil {
-		return errors.Wrapf(err, "error reading file '%s' before s3.Put", fileName)
-	}
 
 	if b.dryRun {
 		grip.Noticef("dry-run: would have uploaded %s -> %s/%s", fileName, b.name, path)
 		return nil
 	}
 
-	// do put in a retry loop:
-	catcher := grip.NewCatcher()
-	backoff := getBackoff()
-	for i := 1; i <= b.numRetries; i++ {
-		err = b.bucket.Put(path, contents, mimeType, b.NewFilePermission, s3.Options{})
-		if err == nil {
-			grip.Debugf("uploaded %s -> %s/%s", fileName, b.name, path)
-			return nil
-		}
+	// do put in a retry loop, re-opening the file each attempt since
+	// the driver consumes the reader it's given.
+	return b.metrics.observe("put", func() error {
+		catcher := grip.NewCatcher()
+		backoff := getBackoff()
+		for i := 1; i <= b.numRetries; i++ {
+			err = b.putOnce(ctx, fileName, path, mimeType, stat.Size())
+			if err == nil {
+				grip.Debugf("uploaded %s -> %s/%s", fileName, b.name, path)
+				b.metrics.recordBytesIn("put", stat.Size())
+				return nil
+			}
+
+			catcher.Add(errors.Wrapf(err, "error s3.PUT for %s/%s on attempt %d", path, b.name, i))
 
-		catcher.Add(errors.Wrapf(err, "error s3.PUT for %s/%s on attempt %d", path, b.name, i))
+			if i < b.numRetries {
+				b.metrics.recordRetry("put")
+				grip.Warningln(err, "retrying...")
+				if sleepErr := sleepOrCancel(ctx, backoff.Duration()); sleepErr != nil {
+					catcher.Add(errors.Wrap(sleepErr, "canceled while retrying s3.PUT"))
+					return catcher.Resolve()
+				}
+				grip.Debugf("retrying s3.GET %d of %d, for %s", i, b.numRetries, path)
+			}
+		}
 
-		if i < b.numRetries {
-			grip.Warningln(err, "retrying...")
-			time.Sleep(backoff.Duration())
-			grip.Debugf("retrying s3.GET %d of %d, for %s", i, b.numRetries, path)
+		if catcher.HasErrors() {
+			return errors.Errorf("could not upload %s/%s in %d attempts. Errors: %s",
+				b.name, path, b.numRetries, catcher.Resolve())
 		}
+
+		return nil
+	})
+}
+
+// putOnce opens fileName and streams it to the driver, so that a
+// single attempt never holds the whole file in memory. Called from a
+// retry loop, so it opens (and closes) the file fresh each attempt.
+func (b *Bucket) putOnce(ctx context.Context, fileName, path, mimeType string, size int64) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return errors.Wrapf(err, "opening file '%s' before s3.Put", fileName)
 	}
+	defer f.Close()
 
-	if catcher.HasErrors() {
-		return errors.Errorf("could not upload %s/%s in %d attempts. Errors: %s",
-			b.name, path, b.numRetries, catcher.Resolve())
+	opts := b.putOptions()
+
+	checksumKey, digest, err := localChecksum(fileName, b.effectiveChecksumAlgorithm())
+	if err != nil {
+		return err
+	}
+	if checksumKey != "" {
+		opts.Metadata = map[string]string{checksumKey: digest}
 	}
 
-	return nil
+	return errors.WithStack(b.driver.Put(ctx, path, f, size, mimeType, b.permission, opts))
 }
 
 // getMimeType takes a file name, attempts to determine the extension
@@ -352,61 +830,92 @@ func getMimeType(fileName string) string {
 // Get writes the content of the S3 object located at "path" to the
 // local file at the "fileName", creating enclosing directories as
 // needed.
-func (b *Bucket) Get(path, fileName string) error {
-	// do put in a retry loop:
-	catcher := grip.NewCatcher()
-
-	var data []byte
-	var err error
-
-	backoff := getBackoff()
-	for i := 1; i <= b.numRetries; i++ {
-		data, err = b.bucket.Get(path)
-
-		if err == nil {
-			grip.Debugf("downloaded %s/%s -> %s", b.name, path, fileName)
-			catcher = grip.NewCatcher() // reset the error handler in the case of success
-			break
+func (b *Bucket) Get(ctx context.Context, path, fileName string) error {
+	dirName := filepath.Dir(fileName)
+	if _, err := os.Stat(dirName); os.IsNotExist(err) {
+		if err = os.MkdirAll(dirName, 0755); err != nil {
+			return errors.Wrap(err, "creating directory for s3.Get operations")
 		}
+		grip.Debugf("created directory '%s' for object %s", dirName, fileName)
+	}
+
+	// do get in a retry loop:
+	return b.metrics.observe("get", func() error {
+		catcher := grip.NewCatcher()
+
+		backoff := getBackoff()
+		for i := 1; i <= b.numRetries; i++ {
+			err := b.getOnce(ctx, path, fileName)
+			if err == nil {
+				grip.Debugf("downloaded %s/%s -> %s", b.name, path, fileName)
+				if stat, statErr := os.Stat(fileName); statErr == nil {
+					b.metrics.recordBytesOut("get", stat.Size())
+				}
+				return nil
+			}
 
-		catcher.Add(errors.Wrap(err, "aws error from s3.Get"))
+			catcher.Add(errors.Wrap(err, "aws error from s3.Get"))
 
-		if i < b.numRetries {
-			grip.Warningln(err, "retrying...")
-			time.Sleep(backoff.Duration())
-			grip.Debugf("retrying s3.GET %d of %d, for %s", i, b.numRetries, path)
+			if i < b.numRetries {
+				b.metrics.recordRetry("get")
+				grip.Warningln(err, "retrying...")
+				if sleepErr := sleepOrCancel(ctx, backoff.Duration()); sleepErr != nil {
+					catcher.Add(errors.Wrap(sleepErr, "canceled while retrying s3.GET"))
+					return catcher.Resolve()
+				}
+				grip.Debugf("retrying s3.GET %d of %d, for %s", i, b.numRetries, path)
+			}
 		}
-	}
 
-	// return early if we encountered an error attempting to build
-	if catcher.HasErrors() {
 		return errors.Errorf("could not download %s/%s in %d attempts. Errors: %s",
 			b.name, path, b.numRetries, catcher.Resolve())
-	}
+	})
+}
 
-	dirName := filepath.Dir(fileName)
-	if _, err = os.Stat(dirName); os.IsNotExist(err) {
-		err = os.MkdirAll(dirName, 0755)
-		if err != nil {
-			return errors.Wrap(err, "creating directory for s3.Get operations")
-		}
-		grip.Debugf("created directory '%s' for object %s", dirName, fileName)
+// getOnce creates (truncating) fileName and streams path into it, so
+// that a single attempt never holds the whole object in memory.
+// Called from a retry loop, so the file is reopened fresh each
+// attempt.
+func (b *Bucket) getOnce(ctx context.Context, path, fileName string) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return errors.Wrapf(err, "creating file '%s' for s3.Get", fileName)
 	}
+	defer f.Close()
 
-	return errors.Wrapf(ioutil.WriteFile(fileName, data, 0644),
-		"writing file %s during s3 get", fileName)
+	return errors.WithStack(b.driver.Get(ctx, path, f, b.getOptions()))
 }
 
-// Delete removes a single object from an S3 bucket.
-func (b *Bucket) Delete(path string) error {
+// Delete removes a single object from an S3 bucket. When the Bucket
+// has a TrashLifetime configured (and UnsafeDelete isn't set), the
+// object is copied under trashKey(path) first, so it can still be
+// recovered with Untrash until EmptyTrash removes it.
+func (b *Bucket) Delete(ctx context.Context, path string) error {
 	grip.Noticef("removing %s.%s", b.name, path)
 
-	return errors.Wrapf(b.bucket.Del(path), "deleting %s from %s", path, b.name)
+	return b.metrics.observe("delete", func() error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if b.dryRun {
+			grip.Infof("dry-run: would delete %s/%s", b.name, path)
+			return nil
+		}
+
+		if b.trashLifetime > 0 && !b.unsafeDelete {
+			if err := b.driver.Copy(ctx, path, trashKey(path), b.permission); err != nil {
+				return errors.Wrapf(err, "trashing %s/%s before delete", b.name, path)
+			}
+		}
+
+		return errors.Wrapf(b.driver.DelMulti(ctx, []string{path}), "deleting %s from %s", path, b.name)
+	})
 }
 
 // DeleteMany takes a variable number of strings, and sends a single
 // request to S3 to delete those keys from the bucket.
-func (b *Bucket) DeleteMany(paths ...string) error {
+func (b *Bucket) DeleteMany(ctx context.Context, paths ...string) error {
 	if len(paths) == 1 {
 		// getting the bucket contents is a comparatively
 		// expensive operation so makes sense to avoid it in
@@ -415,11 +924,11 @@ func (b *Bucket) DeleteMany(paths ...string) error {
 			grip.Infof("dry-run: deleting object %s as a single object in a multi-delete call", paths[0])
 			return nil
 		} else {
-			return errors.Wrap(b.Delete(paths[0]), "single delete operation in multi-delete call")
+			return errors.Wrap(b.Delete(ctx, paths[0]), "single delete operation in multi-delete call")
 		}
 	}
 
-	contents := b.contents("")
+	contents := b.contents(ctx, "")
 	toDelete := make(chan s3.Key, 100)
 	go func() {
 		for _, p := range paths {
@@ -429,22 +938,27 @@ func (b *Bucket) DeleteMany(paths ...string) error {
 				continue
 			}
 
-			toDelete <- key
+			select {
+			case toDelete <- key:
+			case <-ctx.Done():
+				close(toDelete)
+				return
+			}
 		}
 
 		close(toDelete)
 	}()
 
-	return b.deleteGroup(toDelete)
+	return b.deleteGroup(ctx, toDelete)
 }
 
 // DeletePrefix removes all items in a bucket that have key names that
 // begin with a specific prefix.
-func (b *Bucket) DeletePrefix(prefix string) error {
-	return b.deleteGroup(b.list(prefix))
+func (b *Bucket) DeletePrefix(ctx context.Context, prefix string) error {
+	return b.deleteGroup(ctx, b.list(ctx, prefix))
 }
 
-func (b *Bucket) DeleteMatching(prefix, expression string) error {
+func (b *Bucket) DeleteMatching(ctx context.Context, prefix, expression string) error {
 	matcher, err := regexp.Compile(expression)
 	if err != nil {
 		return errors.Wrapf(err,
@@ -456,13 +970,18 @@ func (b *Bucket) DeleteMatching(prefix, expression string) error {
 
 	go func() {
 		var count int
-		list := b.list(prefix)
+		list := b.list(ctx, prefix)
 
 		for item := range list {
 			name := item.Key
 
 			if matcher.MatchString(name) {
-				toDelete <- item
+				select {
+				case toDelete <- item:
+				case <-ctx.Done():
+					close(toDelete)
+					return
+				}
 				count++
 				grip.Debugf("found %s/%s to delete", b.name, name)
 			} else {
@@ -475,28 +994,90 @@ func (b *Bucket) DeleteMatching(prefix, expression string) error {
 		close(toDelete)
 	}()
 
-	return b.deleteGroup(toDelete)
+	return b.deleteGroup(ctx, toDelete)
+}
+
+// deleteKeys extracts the key names from a s3.Delete batch so that
+// deleteGroup can hand them to Driver.DelMulti without leaking the
+// goamz s3.Delete/s3.Object types across the driver boundary.
+func deleteKeys(batch s3.Delete) []string {
+	keys := make([]string, 0, len(batch.Objects))
+	for _, obj := range batch.Objects {
+		keys = append(keys, obj.Key)
+	}
+
+	return keys
+}
+
+// trashPrefix is the key prefix deleteGroup copies objects under
+// before removing the original, when the Bucket has a TrashLifetime
+// configured. See SetTrashLifetime, EmptyTrash, and Untrash.
+const trashPrefix = "trash/"
+
+func trashKey(key string) string {
+	return trashPrefix + key
 }
 
-func (b *Bucket) deleteGroup(items <-chan s3.Key) error {
+func untrashKey(key string) string {
+	return strings.TrimPrefix(key, trashPrefix)
+}
+
+// deleteGroup drains items and removes them from the bucket in
+// batches of up to 1000, the limit DelMulti accepts per request. When
+// the Bucket has a TrashLifetime and UnsafeDelete isn't set, each
+// object is first copied under trashKey(key) via Driver.Copy, and only
+// objects whose copy succeeded are removed, so Untrash can still
+// recover them until EmptyTrash permanently removes the trash copy.
+func (b *Bucket) deleteGroup(ctx context.Context, items <-chan s3.Key) error {
+	trashing := b.trashLifetime > 0 && !b.unsafeDelete
+
 	toDelete := s3.Delete{}
 	count := 0
+	catcher := grip.NewCatcher()
+
+	sendBatch := func(label string) {
+		if len(toDelete.Objects) == 0 {
+			return
+		}
+
+		if b.dryRun {
+			grip.Infof("dry-run: would send %s of delete operations to %s", label, b.name)
+			return
+		}
+
+		grip.Debugf("sending %s of delete operations to %s", label, b.name)
+
+		keys := deleteKeys(toDelete)
+		if trashing {
+			keys = keys[:0]
+			for _, obj := range toDelete.Objects {
+				if err := b.driver.Copy(ctx, obj.Key, trashKey(obj.Key), b.permission); err != nil {
+					catcher.Add(errors.Wrapf(err, "trashing %s/%s before delete", b.name, obj.Key))
+					continue
+				}
+				keys = append(keys, obj.Key)
+			}
+		}
+
+		if len(keys) == 0 {
+			return
+		}
+
+		catcher.Add(b.metrics.observe("delete_multi", func() error {
+			return errors.Wrapf(b.driver.DelMulti(ctx, keys),
+				"%s from %s, %d items encountered error", label, b.name, len(keys))
+		}))
+	}
 
 	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		// DeleteMulti maxes out at 1000 items per request. We
 		// should batch accordingly too.
 		if count == 1000 {
-			if b.dryRun {
-				grip.Infof("dry-run: would send a batch of delete operations to %s", b.name)
-			} else {
-				grip.Debugf("sending a batch of delete operations to %s", b.name)
-
-				return errors.Wrapf(b.bucket.DelMulti(toDelete),
-					"intermediate delete from %s, %d items encountered error",
-					b.name, count)
-			}
-
-			// reset the counters
+			sendBatch("an intermediate batch")
 			toDelete = s3.Delete{}
 			count = 0
 		}
@@ -515,19 +1096,76 @@ func (b *Bucket) deleteGroup(items <-chan s3.Key) error {
 		break
 	}
 
-	if len(toDelete.Objects) > 0 {
+	sendBatch("the last batch")
+
+	return catcher.Resolve()
+}
+
+// Untrash restores a single object that deleteGroup previously moved
+// under the trash/ prefix, copying it back to its original key and
+// then removing the trash copy. key is the object's original name,
+// not its trashKey.
+func (b *Bucket) Untrash(ctx context.Context, key string) error {
+	return b.metrics.observe("untrash", func() error {
 		if b.dryRun {
-			grip.Infof("dry-run: would send last batch of delete operations to %s", b.name)
-		} else {
-			grip.Debugf("sending last batch of delete operations to %s", b.name)
+			grip.Infof("dry-run: would restore %s/%s from trash", b.name, key)
+			return nil
+		}
 
-			return errors.Wrapf(b.bucket.DelMulti(toDelete),
-				"delete from %s, %d items encountered error",
-				b.name, len(toDelete.Objects))
+		if err := b.driver.Copy(ctx, trashKey(key), key, b.permission); err != nil {
+			return errors.Wrapf(err, "restoring %s/%s from trash", b.name, key)
 		}
-	}
 
-	return nil
+		return errors.Wrapf(b.driver.DelMulti(ctx, []string{trashKey(key)}),
+			"removing trash copy of %s/%s after restore", b.name, key)
+	})
+}
+
+// EmptyTrash permanently removes objects under the trash/ prefix that
+// were trashed more than olderThan ago, completing the recovery
+// window SetTrashLifetime establishes. Age is taken from each
+// trashed object's LastModified, which S3 resets every time
+// deleteGroup's Copy call recreates it under trash/.
+func (b *Bucket) EmptyTrash(ctx context.Context, olderThan time.Duration) error {
+	return b.metrics.observe("empty_trash", func() error {
+		cutoff := time.Now().Add(-olderThan)
+
+		var expired []string
+		for item := range b.list(ctx, trashPrefix) {
+			modified, err := time.Parse(time.RFC3339, item.LastModified)
+			if err != nil {
+				grip.Warningf("could not parse last-modified time %q for trashed object %s/%s",
+					item.LastModified, b.name, item.Key)
+				continue
+			}
+
+			if modified.Before(cutoff) {
+				expired = append(expired, item.Key)
+			}
+		}
+
+		if len(expired) == 0 {
+			return nil
+		}
+
+		if b.dryRun {
+			grip.Infof("dry-run: would permanently delete %d trashed items from %s", len(expired), b.name)
+			return nil
+		}
+
+		catcher := grip.NewCatcher()
+		for i := 0; i < len(expired); i += 1000 {
+			end := i + 1000
+			if end > len(expired) {
+				end = len(expired)
+			}
+
+			catcher.Add(errors.Wrapf(b.driver.DelMulti(ctx, expired[i:end]),
+				"permanently deleting trashed items %d:%d from %s", i, end, b.name))
+		}
+
+		return catcher.Resolve()
+	})
 }
 
 // SyncTo takes a local path, typically directory, and an S3 path
@@ -535,10 +1173,29 @@ func (b *Bucket) deleteGroup(items <-chan s3.Key) error {
 // not exist or if the local file has different content from the
 // remote file. All operations execute in the worker pool, and SyncTo
 // waits for all jobs to complete before returning an aggregated error.
-func (b *Bucket) SyncTo(local, prefix string) error {
+//
+// The actual Put for each file happens inside the newSyncToJob it
+// dispatches, so it already benefits from Put's streaming upload path
+// without any change here. The "different content" decision is made
+// up front, via unchanged, which trusts the SHA-256 (or MD5) Put
+// stores as object metadata instead of downloading the remote object
+// to compare; a file found unchanged is never queued. b.checksums is
+// reset here so a sync run issues at most one HEAD per object even
+// across retries. Server-side encryption and storage class settings
+// apply the same way they do to a direct Put, since newSyncToJob's
+// upload goes through Put itself.
+func (b *Bucket) SyncTo(ctx context.Context, local, prefix string, opts SyncOptions) error {
+	return b.metrics.observe("sync_to", func() error {
+		return b.syncTo(ctx, local, prefix, opts)
+	})
+}
+
+func (b *Bucket) syncTo(ctx context.Context, local, prefix string, opts SyncOptions) error {
 	grip.Infof("sync push %s -> %s/%s", local, b.name, prefix)
 
-	remote := b.contents(prefix)
+	b.checksums = &remoteChecksumCache{data: make(map[string]string)}
+
+	remote := b.contents(ctx, prefix)
 
 	var counter int
 	catcher := grip.NewCatcher()
@@ -558,7 +1215,13 @@ func (b *Bucket) SyncTo(local, prefix string) error {
 		remoteFile, ok := remote[keyName]
 		if !ok {
 			remoteFile = s3.Key{Key: keyName}
+		} else if same, err := b.unchanged(ctx, path, keyName); err != nil {
+			return err
+		} else if same {
+			grip.Debugf("skipping upload of unchanged file %s -> %s/%s", path, b.name, keyName)
+			return nil
 		}
+
 		job := newSyncToJob(path, remoteFile, b)
 
 		counter++
@@ -593,12 +1256,35 @@ func (b *Bucket) SyncTo(local, prefix string) error {
 // download files if the content of the local file have *not* changed.
 // All operations execute in the worker pool, and SyncTo waits for all
 // jobs to complete before returning an aggregated erro
-func (b *Bucket) SyncFrom(local, prefix string) error {
+//
+// Each download happens inside the newSyncFromJob it dispatches, so
+// it already benefits from Get's streaming download path without any
+// change here. The "unchanged" decision, like SyncTo's, is made up
+// front via unchanged before a job is ever queued.
+func (b *Bucket) SyncFrom(ctx context.Context, local, prefix string, opts SyncOptions) error {
+	return b.metrics.observe("sync_from", func() error {
+		return b.syncFrom(ctx, local, prefix, opts)
+	})
+}
+
+func (b *Bucket) syncFrom(ctx context.Context, local, prefix string, opts SyncOptions) error {
 	catcher := grip.NewCatcher()
 	grip.Infof("sync pull %s/%s -> %s", b.name, prefix, local)
 
-	for remote := range b.list(prefix) {
-		job := newSyncFromJob(filepath.Join(local, remote.Key[len(prefix):]), remote, b)
+	b.checksums = &remoteChecksumCache{data: make(map[string]string)}
+
+	for remote := range b.list(ctx, prefix) {
+		dest := filepath.Join(local, remote.Key[len(prefix):])
+
+		if same, err := b.unchanged(ctx, dest, remote.Key); err != nil {
+			catcher.Add(err)
+			continue
+		} else if same {
+			grip.Debugf("skipping download of unchanged file %s/%s -> %s", b.name, remote.Key, dest)
+			continue
+		}
+
+		job := newSyncFromJob(dest, remote, b)
 
 		// add the job to the queue
 		catcher.Add(errors.Wrap(b.queue.Put(job),
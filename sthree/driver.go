@@ -0,0 +1,74 @@
+package sthree
+
+import (
+	"context"
+	"io"
+)
+
+// DriverType selects which S3 client implementation a Bucket uses.
+type DriverType string
+
+const (
+	// DriverGoamz keeps curator's long-standing behavior of
+	// talking to S3 through the goamz/goamz client. It remains the
+	// default so existing deployments don't need to change
+	// anything to keep working.
+	DriverGoamz DriverType = "goamz"
+
+	// DriverAWSV2 talks to S3 through aws-sdk-go-v2, which (unlike
+	// goamz) understands IAM role/instance-profile credentials,
+	// EC2 metadata refresh, IMDSv2, both virtual-hosted and
+	// path-style addressing, and the SDK's modern retry/error
+	// taxonomy.
+	DriverAWSV2 DriverType = "aws-sdk-go-v2"
+)
+
+// ObjectInfo is the driver-agnostic description of a single object
+// returned from a List call.
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ETag         string
+	LastModified string
+}
+
+// Driver is the set of raw S3 operations a Bucket needs from its
+// underlying client library. Bucket owns retry/backoff, logging, and
+// the worker queue; Driver only has to make the single request it's
+// asked to make.
+type Driver interface {
+	// Put streams body to path. size is the total number of bytes
+	// body will yield; drivers that require a Content-Length ahead
+	// of the request (or that can't do true multipart streaming)
+	// may use it instead of buffering to find out.
+	Put(ctx context.Context, path string, body io.Reader, size int64, contentType string, perm ObjectPermission, opts PutOptions) error
+	// Get streams path into w, so that callers can pass an *os.File
+	// and never hold the whole object in memory.
+	Get(ctx context.Context, path string, w io.WriterAt, opts GetOptions) error
+	Exists(ctx context.Context, path string) (bool, error)
+	// Head returns path's object metadata (the driver-agnostic
+	// equivalent of goamz's "x-amz-meta-*" headers), without
+	// fetching its body. Used to read back the content digest Put
+	// stored under PutOptions.Metadata.
+	Head(ctx context.Context, path string) (map[string]string, error)
+	List(ctx context.Context, prefix string) (<-chan ObjectInfo, error)
+	DelMulti(ctx context.Context, paths []string) error
+	// Copy duplicates the object at srcPath to dstPath within the
+	// same bucket, server-side, without downloading and re-uploading
+	// its body. Used by Bucket's trash semantics to move an object
+	// under the trash/ prefix before removing the original.
+	Copy(ctx context.Context, srcPath, dstPath string, perm ObjectPermission) error
+}
+
+// newDriver builds the Driver selected by creds.Driver, defaulting to
+// DriverGoamz for configurations that predate this field.
+func newDriver(creds AWSConnectionConfiguration, bucketName string) Driver {
+	switch creds.Driver {
+	case DriverAWSV2:
+		return newAWSV2Driver(creds, bucketName)
+	case DriverGoamz, "":
+		return newGoamzDriver(creds, bucketName)
+	default:
+		return newGoamzDriver(creds, bucketName)
+	}
+}
@@ -0,0 +1,276 @@
+package sthree
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/ec2rolecreds"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/pkg/errors"
+	"github.com/tychoish/grip"
+)
+
+// awsv2Driver implements Driver on top of aws-sdk-go-v2's s3 client.
+// Unlike goamzDriver, it resolves credentials through the SDK's
+// default chain (environment, shared config, and finally EC2
+// role/instance-profile credentials), understands custom endpoints
+// for S3-compatible services, and lets the SDK's own retry/error
+// taxonomy surface through.
+type awsv2Driver struct {
+	client     *s3.Client
+	bucketName string
+
+	// credsExpireAt is tracked for logging only: the SDK itself
+	// refreshes EC2 role credentials transparently, but operators
+	// have asked to see when a credential refresh is imminent.
+	credsExpireAt time.Time
+}
+
+func newAWSV2Driver(creds AWSConnectionConfiguration, bucketName string) Driver {
+	ctx := context.Background()
+
+	opts := []func(*awsconfig.LoadOptions) error{
+		awsconfig.WithRegion(creds.Region.Name),
+	}
+
+	if creds.Auth.AccessKey != "" {
+		opts = append(opts, awsconfig.WithCredentialsProvider(
+			aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+				return aws.Credentials{
+					AccessKeyID:     creds.Auth.AccessKey,
+					SecretAccessKey: creds.Auth.SecretKey,
+					Source:          "curator-static",
+				}, nil
+			})))
+	}
+
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		grip.Error(errors.Wrap(err, "loading aws-sdk-go-v2 config, falling back to SDK defaults"))
+	}
+
+	d := &awsv2Driver{bucketName: bucketName}
+
+	if cfg.Credentials == nil || creds.Auth.AccessKey == "" {
+		ec2Provider := ec2rolecreds.New()
+		cfg.Credentials = aws.NewCredentialsCache(ec2Provider)
+
+		if roleCreds, roleErr := ec2Provider.Retrieve(ctx); roleErr == nil {
+			d.credsExpireAt = roleCreds.Expires
+			grip.Debugf("resolved EC2 role credentials for %s, expiring at %s", bucketName, d.credsExpireAt)
+		}
+	}
+
+	d.client = s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if creds.Endpoint != "" {
+			o.BaseEndpoint = aws.String(creds.Endpoint)
+			o.UsePathStyle = true
+		}
+	})
+
+	return d
+}
+
+// Put streams body to S3 through an s3manager-equivalent Uploader, so
+// multi-gigabyte artifacts never need to fit in memory. opts.PartSize
+// and opts.Concurrency configure the multipart chunking and the
+// number of parts in flight at once; zero values leave the manager's
+// own defaults (5 MiB parts, 5 workers) in place.
+func (d *awsv2Driver) Put(ctx context.Context, path string, body io.Reader, size int64, contentType string, perm ObjectPermission, opts PutOptions) error {
+	uploader := manager.NewUploader(d.client, func(u *manager.Uploader) {
+		if opts.PartSize > 0 {
+			u.PartSize = opts.PartSize
+		}
+		if opts.Concurrency > 0 {
+			u.Concurrency = opts.Concurrency
+		}
+	})
+
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(d.bucketName),
+		Key:         aws.String(path),
+		Body:        body,
+		ContentType: aws.String(contentType),
+		ACL:         awsv2ACL(perm),
+		Metadata:    opts.Metadata,
+	}
+
+	switch opts.ServerSideEncryption {
+	case SSEAES256:
+		input.ServerSideEncryption = types.ServerSideEncryptionAes256
+	case SSEKMS:
+		input.ServerSideEncryption = types.ServerSideEncryptionAwsKms
+		input.SSEKMSKeyId = aws.String(opts.SSEKMSKeyID)
+	}
+
+	if opts.SSECustomerKey != nil {
+		input.SSECustomerAlgorithm = aws.String(opts.SSECustomerKey.Algorithm)
+		input.SSECustomerKey = aws.String(opts.SSECustomerKey.Key)
+		input.SSECustomerKeyMD5 = aws.String(opts.SSECustomerKey.md5Base64())
+	}
+
+	if opts.StorageClass != "" {
+		input.StorageClass = types.StorageClass(opts.StorageClass)
+	}
+
+	_, err := uploader.Upload(ctx, input)
+
+	return errors.WithStack(err)
+}
+
+// Get streams the object at path into w through an s3manager-
+// equivalent Downloader, using concurrent ranged GETs instead of
+// buffering the whole object; see Put for opts.PartSize/Concurrency.
+// opts.SSECustomerKey is required when the object was uploaded with
+// SSE-C, since S3 won't decrypt it without the matching key presented
+// on the GET.
+func (d *awsv2Driver) Get(ctx context.Context, path string, w io.WriterAt, opts GetOptions) error {
+	downloader := manager.NewDownloader(d.client, func(dl *manager.Downloader) {
+		if opts.PartSize > 0 {
+			dl.PartSize = opts.PartSize
+		}
+		if opts.Concurrency > 0 {
+			dl.Concurrency = opts.Concurrency
+		}
+	})
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(d.bucketName),
+		Key:    aws.String(path),
+	}
+
+	if opts.SSECustomerKey != nil {
+		input.SSECustomerAlgorithm = aws.String(opts.SSECustomerKey.Algorithm)
+		input.SSECustomerKey = aws.String(opts.SSECustomerKey.Key)
+		input.SSECustomerKeyMD5 = aws.String(opts.SSECustomerKey.md5Base64())
+	}
+
+	_, err := downloader.Download(ctx, w, input)
+
+	return errors.WithStack(err)
+}
+
+func (d *awsv2Driver) Exists(ctx context.Context, path string) (bool, error) {
+	_, err := d.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(d.bucketName),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return false, nil
+		}
+
+		return false, errors.WithStack(err)
+	}
+
+	return true, nil
+}
+
+// Head returns path's object metadata as set via PutObjectInput.Metadata.
+// The SDK hands metadata keys back lower-cased, so they're put into
+// canonical header form ("Content-Sha256"), matching goamzDriver.Head
+// and the keys Put was given.
+func (d *awsv2Driver) Head(ctx context.Context, path string) (map[string]string, error) {
+	out, err := d.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(d.bucketName),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	meta := make(map[string]string, len(out.Metadata))
+	for k, v := range out.Metadata {
+		meta[http.CanonicalHeaderKey(k)] = v
+	}
+
+	return meta, nil
+}
+
+func (d *awsv2Driver) List(ctx context.Context, prefix string) (<-chan ObjectInfo, error) {
+	output := make(chan ObjectInfo, 100)
+
+	go func() {
+		defer close(output)
+
+		paginator := s3.NewListObjectsV2Paginator(d.client, &s3.ListObjectsV2Input{
+			Bucket: aws.String(d.bucketName),
+			Prefix: aws.String(prefix),
+		})
+
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				grip.Error(errors.Wrap(err, "listing page of s3 objects"))
+				return
+			}
+
+			for _, obj := range page.Contents {
+				info := ObjectInfo{
+					Key:  aws.ToString(obj.Key),
+					ETag: aws.ToString(obj.ETag),
+				}
+				if obj.Size != nil {
+					info.Size = *obj.Size
+				}
+				if obj.LastModified != nil {
+					info.LastModified = obj.LastModified.Format(time.RFC3339)
+				}
+
+				select {
+				case output <- info:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return output, nil
+}
+
+// Copy issues a server-side CopyObject, so trashing an object doesn't
+// round-trip its body through this process.
+func (d *awsv2Driver) Copy(ctx context.Context, srcPath, dstPath string, perm ObjectPermission) error {
+	_, err := d.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(d.bucketName),
+		CopySource: aws.String(fmt.Sprintf("%s/%s", d.bucketName, srcPath)),
+		Key:        aws.String(dstPath),
+		ACL:        awsv2ACL(perm),
+	})
+
+	return errors.WithStack(err)
+}
+
+func (d *awsv2Driver) DelMulti(ctx context.Context, keys []string) error {
+	objects := make([]types.ObjectIdentifier, 0, len(keys))
+	for _, key := range keys {
+		objects = append(objects, types.ObjectIdentifier{Key: aws.String(key)})
+	}
+
+	_, err := d.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+		Bucket: aws.String(d.bucketName),
+		Delete: &types.Delete{Objects: objects},
+	})
+
+	return errors.WithStack(err)
+}
+
+// awsv2ACL translates the backend-agnostic ObjectPermission into the
+// canned ACL type aws-sdk-go-v2 expects.
+func awsv2ACL(perm ObjectPermission) types.ObjectCannedACL {
+	switch perm {
+	case PermissionPublicRead:
+		return types.ObjectCannedACLPublicRead
+	default:
+		return types.ObjectCannedACLPrivate
+	}
+}
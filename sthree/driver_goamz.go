@@ -0,0 +1,184 @@
+package sthree
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/goamz/goamz/s3"
+	"github.com/pkg/errors"
+)
+
+// goamzDriver implements Driver on top of the goamz/goamz client that
+// curator has always used. It exists so that DriverGoamz stays the
+// zero-value default and existing deployments don't have to change
+// anything to keep their current behavior.
+type goamzDriver struct {
+	bucket *s3.Bucket
+}
+
+func newGoamzDriver(creds AWSConnectionConfiguration, bucketName string) Driver {
+	return &goamzDriver{
+		bucket: s3.New(creds.Auth, creds.Region).Bucket(bucketName),
+	}
+}
+
+// Put buffers body into memory before handing it to goamz, which only
+// accepts a []byte: goamz predates multipart streaming support, so
+// PartSize and Concurrency in opts are ignored on this driver.
+func (d *goamzDriver) Put(ctx context.Context, path string, body io.Reader, size int64, contentType string, perm ObjectPermission, opts PutOptions) error {
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return errors.Wrap(err, "buffering upload body for goamz driver")
+	}
+
+	s3opts := s3.Options{}
+	if len(opts.Metadata) > 0 {
+		s3opts.Meta = make(map[string][]string, len(opts.Metadata))
+		for k, v := range opts.Metadata {
+			s3opts.Meta[k] = []string{v}
+		}
+	}
+
+	switch opts.ServerSideEncryption {
+	case SSEAES256:
+		s3opts.ServerSideEncryption = string(SSEAES256)
+	case SSEKMS:
+		s3opts.ServerSideEncryption = string(SSEKMS)
+		s3opts.SSEKMSKeyId = opts.SSEKMSKeyID
+	}
+
+	if opts.SSECustomerKey != nil {
+		s3opts.SSECustomerAlgorithm = opts.SSECustomerKey.Algorithm
+		s3opts.SSECustomerKey = opts.SSECustomerKey.Key
+		s3opts.SSECustomerKeyMD5 = opts.SSECustomerKey.md5Base64()
+	}
+
+	if opts.StorageClass != "" {
+		s3opts.StorageClass = string(opts.StorageClass)
+	}
+
+	return errors.WithStack(d.bucket.Put(path, data, contentType, goamzACL(perm), s3opts))
+}
+
+// Get buffers the whole object into memory before writing it to w:
+// goamz has no streaming download support, so opts.PartSize and
+// opts.Concurrency are ignored on this driver. opts.SSECustomerKey is
+// required when the object was uploaded with SSE-C, since S3 won't
+// decrypt it without the matching key presented on the GET.
+func (d *goamzDriver) Get(ctx context.Context, path string, w io.WriterAt, opts GetOptions) error {
+	var data []byte
+	var err error
+
+	if opts.SSECustomerKey != nil {
+		headers := map[string][]string{
+			"x-amz-server-side-encryption-customer-algorithm": {opts.SSECustomerKey.Algorithm},
+			"x-amz-server-side-encryption-customer-key":       {opts.SSECustomerKey.Key},
+			"x-amz-server-side-encryption-customer-key-MD5":   {opts.SSECustomerKey.md5Base64()},
+		}
+		data, err = d.bucket.GetWithHeaders(path, headers)
+	} else {
+		data, err = d.bucket.Get(path)
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	_, err = w.WriteAt(data, 0)
+	return errors.WithStack(err)
+}
+
+func (d *goamzDriver) Exists(ctx context.Context, path string) (bool, error) {
+	exists, err := d.bucket.Exists(path)
+	return exists, errors.WithStack(err)
+}
+
+// Head issues a HEAD request and pulls out the "x-amz-meta-*"
+// response headers, stripping the prefix so callers see the same
+// metadata keys they passed in to Put.
+func (d *goamzDriver) Head(ctx context.Context, path string) (map[string]string, error) {
+	resp, err := d.bucket.Head(path, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+
+	const metaPrefix = "X-Amz-Meta-"
+	meta := make(map[string]string)
+	for k := range resp.Header {
+		if strings.HasPrefix(strings.ToLower(k), strings.ToLower(metaPrefix)) {
+			meta[k[len(metaPrefix):]] = resp.Header.Get(k)
+		}
+	}
+
+	return meta, nil
+}
+
+func (d *goamzDriver) List(ctx context.Context, prefix string) (<-chan ObjectInfo, error) {
+	output := make(chan ObjectInfo, 100)
+
+	go func() {
+		defer close(output)
+
+		var lastKey string
+		for {
+			results, err := d.bucket.List(prefix, "", lastKey, 1000)
+			if err != nil {
+				return
+			}
+
+			for _, key := range results.Contents {
+				lastKey = key.Key
+
+				info := ObjectInfo{
+					Key:          key.Key,
+					Size:         key.Size,
+					ETag:         key.ETag,
+					LastModified: key.LastModified,
+				}
+
+				select {
+				case output <- info:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			if !results.IsTruncated {
+				return
+			}
+		}
+	}()
+
+	return output, nil
+}
+
+// Copy issues a server-side PutCopy, so trashing an object doesn't
+// round-trip its body through this process.
+func (d *goamzDriver) Copy(ctx context.Context, srcPath, dstPath string, perm ObjectPermission) error {
+	source := fmt.Sprintf("/%s/%s", d.bucket.Name, srcPath)
+	_, err := d.bucket.PutCopy(dstPath, goamzACL(perm), s3.CopyOptions{}, source)
+	return errors.WithStack(err)
+}
+
+func (d *goamzDriver) DelMulti(ctx context.Context, keys []string) error {
+	batch := s3.Delete{}
+	for _, key := range keys {
+		batch.Objects = append(batch.Objects, s3.Object{Key: key})
+	}
+
+	return errors.WithStack(d.bucket.DelMulti(batch))
+}
+
+// goamzACL translates the backend-agnostic ObjectPermission into the
+// s3.ACL value goamz expects.
+func goamzACL(perm ObjectPermission) s3.ACL {
+	switch perm {
+	case PermissionPublicRead:
+		return s3.PublicRead
+	default:
+		return s3.Private
+	}
+}
@@ -0,0 +1,85 @@
+package sthree
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+
+	"github.com/pkg/errors"
+)
+
+// ServerSideEncryption selects the server-side encryption S3 applies
+// to an uploaded object. The empty value leaves objects unencrypted
+// (or encrypted only by whatever default bucket encryption the
+// bucket's S3 console/IaC configuration sets).
+type ServerSideEncryption string
+
+const (
+	SSEAES256 ServerSideEncryption = "AES256"
+	SSEKMS    ServerSideEncryption = "aws:kms"
+)
+
+// SSECustomerKey holds an SSE-C (customer-provided key) configuration.
+// Key is the raw (not base64-encoded) 256-bit key; KeyMD5 is computed
+// automatically from Key when left empty. SSE-C is mutually exclusive
+// with ServerSideEncryption/SSEKMSKeyID: S3 rejects a request that
+// sets both.
+type SSECustomerKey struct {
+	Algorithm string
+	Key       string
+	KeyMD5    string
+}
+
+// md5Base64 returns the base64-encoded MD5 digest of k.Key, computing
+// it when k.KeyMD5 wasn't set explicitly.
+func (k SSECustomerKey) md5Base64() string {
+	if k.KeyMD5 != "" {
+		return k.KeyMD5
+	}
+
+	sum := md5.Sum([]byte(k.Key))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// StorageClass selects the S3 storage tier an uploaded object is
+// written to. The empty value leaves objects on the bucket's default
+// class (ordinarily STANDARD).
+type StorageClass string
+
+const (
+	StorageClassStandard           StorageClass = "STANDARD"
+	StorageClassStandardIA         StorageClass = "STANDARD_IA"
+	StorageClassIntelligentTiering StorageClass = "INTELLIGENT_TIERING"
+	StorageClassGlacier            StorageClass = "GLACIER"
+)
+
+// validateEncryptionConfig checks that a Bucket's server-side
+// encryption and storage class settings are internally consistent,
+// so that Open fails fast on misconfiguration instead of every
+// subsequent Put returning an S3 API error.
+func validateEncryptionConfig(sse ServerSideEncryption, kmsKeyID string, sseC *SSECustomerKey, class StorageClass) error {
+	switch sse {
+	case "", SSEAES256, SSEKMS:
+	default:
+		return errors.Errorf("unrecognized server-side encryption %q, expected AES256 or aws:kms", sse)
+	}
+
+	if sse == SSEKMS && kmsKeyID == "" {
+		return errors.New("SSEKMSKeyID is required when ServerSideEncryption is aws:kms")
+	}
+
+	if sse != "" && sseC != nil {
+		return errors.New("ServerSideEncryption and an SSE-C customer key are mutually exclusive")
+	}
+
+	if sseC != nil && sseC.Key == "" {
+		return errors.New("SSECustomerKey.Key must not be empty")
+	}
+
+	switch class {
+	case "", StorageClassStandard, StorageClassStandardIA, StorageClassIntelligentTiering, StorageClassGlacier:
+	default:
+		return errors.Errorf("unrecognized storage class %q", class)
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package sthree
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tychoish/grip"
+)
+
+// bucketMetrics is modeled on the volumeMetricsVecs pattern used by
+// the Arvados keepstore S3 volume: a small set of *Vec collectors
+// keyed by operation name, registered once per Bucket and labeled
+// with bucket/region/driver so a single Prometheus registry can track
+// many buckets at once. A nil *bucketMetrics is always safe to call
+// into, so Buckets that never call WithMetrics pay no overhead.
+type bucketMetrics struct {
+	ops      *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	retries  *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+	inFlight *prometheus.GaugeVec
+	bytesIn  *prometheus.CounterVec
+	bytesOut *prometheus.CounterVec
+}
+
+func newBucketMetrics(reg *prometheus.Registry, labels prometheus.Labels) *bucketMetrics {
+	m := &bucketMetrics{
+		ops: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   "curator",
+			Subsystem:   "sthree",
+			Name:        "operations_total",
+			Help:        "Total number of Bucket operations, by operation name.",
+			ConstLabels: labels,
+		}, []string{"operation"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   "curator",
+			Subsystem:   "sthree",
+			Name:        "operation_errors_total",
+			Help:        "Total number of Bucket operation errors, by operation name.",
+			ConstLabels: labels,
+		}, []string{"operation"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   "curator",
+			Subsystem:   "sthree",
+			Name:        "operation_retries_total",
+			Help:        "Total number of retry attempts taken by the backoff loop, by operation name.",
+			ConstLabels: labels,
+		}, []string{"operation"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   "curator",
+			Subsystem:   "sthree",
+			Name:        "operation_duration_seconds",
+			Help:        "Latency of completed Bucket operations, by operation name.",
+			ConstLabels: labels,
+			Buckets:     prometheus.DefBuckets,
+		}, []string{"operation"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   "curator",
+			Subsystem:   "sthree",
+			Name:        "operations_in_flight",
+			Help:        "Number of Bucket operations currently in flight, by operation name.",
+			ConstLabels: labels,
+		}, []string{"operation"}),
+		bytesIn: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   "curator",
+			Subsystem:   "sthree",
+			Name:        "bytes_in_total",
+			Help:        "Total bytes uploaded to S3, by operation name.",
+			ConstLabels: labels,
+		}, []string{"operation"}),
+		bytesOut: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   "curator",
+			Subsystem:   "sthree",
+			Name:        "bytes_out_total",
+			Help:        "Total bytes downloaded from S3, by operation name.",
+			ConstLabels: labels,
+		}, []string{"operation"}),
+	}
+
+	for _, c := range []prometheus.Collector{m.ops, m.errors, m.retries, m.latency, m.inFlight, m.bytesIn, m.bytesOut} {
+		// A Bucket that re-registers against a Registry it's already
+		// registered with (e.g. a second WithMetrics call) shouldn't
+		// panic; it just keeps reporting through the first set of
+		// collectors.
+		if err := reg.Register(c); err != nil {
+			grip.CatchError(err)
+		}
+	}
+
+	return m
+}
+
+// observe wraps op, recording its in-flight gauge, latency, and
+// success/error counters. Retries within op's own backoff loop are
+// tracked separately via recordRetry, since observe only sees the net
+// outcome across all attempts.
+func (m *bucketMetrics) observe(operation string, fn func() error) error {
+	if m == nil {
+		return fn()
+	}
+
+	m.inFlight.WithLabelValues(operation).Inc()
+	start := time.Now()
+
+	err := fn()
+
+	m.inFlight.WithLabelValues(operation).Dec()
+	m.latency.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	m.ops.WithLabelValues(operation).Inc()
+	if err != nil {
+		m.errors.WithLabelValues(operation).Inc()
+	}
+
+	return err
+}
+
+// start begins timing an operation that completes asynchronously,
+// such as list's background goroutine, returning a function the
+// caller invokes on completion with the operation's outcome.
+func (m *bucketMetrics) start(operation string) func(err error) {
+	if m == nil {
+		return func(error) {}
+	}
+
+	m.inFlight.WithLabelValues(operation).Inc()
+	begin := time.Now()
+
+	return func(err error) {
+		m.inFlight.WithLabelValues(operation).Dec()
+		m.latency.WithLabelValues(operation).Observe(time.Since(begin).Seconds())
+		m.ops.WithLabelValues(operation).Inc()
+		if err != nil {
+			m.errors.WithLabelValues(operation).Inc()
+		}
+	}
+}
+
+func (m *bucketMetrics) recordRetry(operation string) {
+	if m == nil {
+		return
+	}
+
+	m.retries.WithLabelValues(operation).Inc()
+}
+
+func (m *bucketMetrics) recordBytesIn(operation string, n int64) {
+	if m == nil || n <= 0 {
+		return
+	}
+
+	m.bytesIn.WithLabelValues(operation).Add(float64(n))
+}
+
+func (m *bucketMetrics) recordBytesOut(operation string, n int64) {
+	if m == nil || n <= 0 {
+		return
+	}
+
+	m.bytesOut.WithLabelValues(operation).Add(float64(n))
+}